@@ -0,0 +1,621 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type Analyzer interface {
+	Columns() []string
+	Analyze(obj *unstructured.Unstructured) map[string]int
+}
+
+// Average is the value of a metric per counted object, e.g. containers per pod.
+type Average struct {
+	Column string
+	Metric string
+}
+
+// Averager is implemented by analyzers with averages. Averages can't be summed
+// up like metrics, they're derived from the summed metric and the count of a
+// row, or a subtotal, when rendering.
+type Averager interface {
+	Averages() []Average
+}
+
+type ManagedFieldsAnalyzer struct {
+	MaxEntries int
+	MaxBytes   int
+}
+
+func (mfa ManagedFieldsAnalyzer) Columns() []string {
+	return []string{"managedFieldsBloated", "managedFieldsBytes"}
+}
+
+func (mfa ManagedFieldsAnalyzer) Analyze(obj *unstructured.Unstructured) map[string]int {
+	managedFields := obj.GetManagedFields()
+
+	size := 0
+	if len(managedFields) > 0 {
+		b, err := json.Marshal(managedFields)
+		if err == nil {
+			size = len(b)
+		}
+	}
+
+	bloated := 0
+	if (mfa.MaxEntries > 0 && len(managedFields) > mfa.MaxEntries) || (mfa.MaxBytes > 0 && size > mfa.MaxBytes) {
+		bloated = 1
+	}
+
+	return map[string]int{
+		"managedFieldsBloated": bloated,
+		"managedFieldsBytes":   size,
+	}
+}
+
+type ReplicasAnalyzer struct{}
+
+func (ra ReplicasAnalyzer) Columns() []string {
+	return []string{"replicas", "readyReplicas", "availableReplicas"}
+}
+
+func (ra ReplicasAnalyzer) Analyze(obj *unstructured.Unstructured) map[string]int {
+	if groupOf(obj) != "apps" {
+		return nil
+	}
+
+	var fields [3][]string
+	switch obj.GetKind() {
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		fields = [3][]string{{"spec", "replicas"}, {"status", "readyReplicas"}, {"status", "availableReplicas"}}
+	case "DaemonSet":
+		fields = [3][]string{{"status", "desiredNumberScheduled"}, {"status", "numberReady"}, {"status", "numberAvailable"}}
+	default:
+		return nil
+	}
+
+	ret := map[string]int{}
+	for i, column := range ra.Columns() {
+		n, _, _ := unstructured.NestedInt64(obj.Object, fields[i]...)
+		ret[column] = int(n)
+	}
+	return ret
+}
+
+// DeadlineAnalyzer is implemented by analyzers whose metrics change with the
+// clock rather than with the object, e.g. a job turning stale. A metric counts
+// 1 from its deadline on, which is checked whenever the counts are read so
+// long-running modes keep up without the object being updated.
+type DeadlineAnalyzer interface {
+	Deadlines(obj *unstructured.Unstructured) map[string]time.Time
+}
+
+type StaleJobsAnalyzer struct {
+	After time.Duration
+}
+
+func (sja StaleJobsAnalyzer) Columns() []string {
+	return []string{"staleJobs"}
+}
+
+func (sja StaleJobsAnalyzer) Analyze(obj *unstructured.Unstructured) map[string]int {
+	if obj.GetKind() != "Job" || groupOf(obj) != "batch" {
+		return nil
+	}
+	return map[string]int{"staleJobs": 0}
+}
+
+func (sja StaleJobsAnalyzer) Deadlines(obj *unstructured.Unstructured) map[string]time.Time {
+	if obj.GetKind() != "Job" || groupOf(obj) != "batch" || jobStatus(obj) != "active" {
+		return nil
+	}
+	return map[string]time.Time{"staleJobs": obj.GetCreationTimestamp().Add(sja.After)}
+}
+
+type RestartsAnalyzer struct {
+	Threshold int
+}
+
+func (ra RestartsAnalyzer) Columns() []string {
+	if ra.Threshold > 0 {
+		return []string{"restarts", "restartingPods"}
+	}
+	return []string{"restarts"}
+}
+
+func (ra RestartsAnalyzer) Analyze(obj *unstructured.Unstructured) map[string]int {
+	if obj.GetKind() != "Pod" || groupOf(obj) != "" {
+		return nil
+	}
+
+	restarts := 0
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", field)
+		for _, item := range statuses {
+			status, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			n, _, _ := unstructured.NestedInt64(status, "restartCount")
+			restarts += int(n)
+		}
+	}
+
+	ret := map[string]int{"restarts": restarts}
+	if ra.Threshold > 0 {
+		ret["restartingPods"] = 0
+		if restarts > ra.Threshold {
+			ret["restartingPods"] = 1
+		}
+	}
+	return ret
+}
+
+type ContainersAnalyzer struct{}
+
+func (ca ContainersAnalyzer) Columns() []string {
+	return []string{"containers", "singleContainerPods", "twoContainerPods", "multiContainerPods", "initContainers", "ephemeralContainers"}
+}
+
+func (ca ContainersAnalyzer) Averages() []Average {
+	return []Average{{Column: "avgContainersPerPod", Metric: "containers"}}
+}
+
+func (ca ContainersAnalyzer) Analyze(obj *unstructured.Unstructured) map[string]int {
+	if obj.GetKind() != "Pod" || groupOf(obj) != "" {
+		return nil
+	}
+
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "containers")
+	initContainers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "initContainers")
+
+	// debug containers can't be removed from a pod, only the running ones are
+	// still active.
+	ephemeralContainers := 0
+	statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", "ephemeralContainerStatuses")
+	for _, item := range statuses {
+		status, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, running, _ := unstructured.NestedMap(status, "state", "running"); running {
+			ephemeralContainers++
+		}
+	}
+
+	ret := map[string]int{
+		"containers":          len(containers),
+		"singleContainerPods": 0,
+		"twoContainerPods":    0,
+		"multiContainerPods":  0,
+		"initContainers":      len(initContainers),
+		"ephemeralContainers": ephemeralContainers,
+	}
+	switch {
+	case len(containers) >= 3:
+		ret["multiContainerPods"] = 1
+	case len(containers) == 2:
+		ret["twoContainerPods"] = 1
+	case len(containers) == 1:
+		ret["singleContainerPods"] = 1
+	}
+	return ret
+}
+
+type RequiredLabelsAnalyzer struct {
+	Labels []string
+}
+
+func (rla RequiredLabelsAnalyzer) Columns() []string {
+	return []string{"missingRequiredLabels"}
+}
+
+func (rla RequiredLabelsAnalyzer) Analyze(obj *unstructured.Unstructured) map[string]int {
+	labels := obj.GetLabels()
+	for _, label := range rla.Labels {
+		if _, ok := labels[label]; !ok {
+			return map[string]int{"missingRequiredLabels": 1}
+		}
+	}
+	return map[string]int{"missingRequiredLabels": 0}
+}
+
+type IngressAnalyzer struct{}
+
+func (ia IngressAnalyzer) Columns() []string {
+	return []string{"hosts", "rules", "paths"}
+}
+
+func (ia IngressAnalyzer) Analyze(obj *unstructured.Unstructured) map[string]int {
+	if obj.GetKind() != "Ingress" {
+		return nil
+	}
+
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	hosts := ingressHosts(obj)
+	paths := 0
+	for _, item := range rules {
+		rule, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ps, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+		paths += len(ps)
+	}
+	return map[string]int{"hosts": len(hosts), "rules": len(rules), "paths": paths}
+}
+
+func ingressHosts(obj *unstructured.Unstructured) map[string]bool {
+	hosts := map[string]bool{}
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	for _, item := range rules {
+		rule, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if host, _, _ := unstructured.NestedString(rule, "host"); host != "" {
+			hosts[host] = true
+		}
+	}
+	return hosts
+}
+
+type Classifier interface {
+	Columns() []string
+	Classify(obj *unstructured.Unstructured) map[string]string
+}
+
+var breakdownClassifiers = []Classifier{
+	EventClassifier{},
+	DeploymentClassifier{},
+	JobClassifier{},
+	VolumeClassifier{},
+	NodeClassifier{},
+	WebhookClassifier{},
+	NamespaceClassifier{},
+}
+
+func groupOf(obj *unstructured.Unstructured) string {
+	return obj.GroupVersionKind().Group
+}
+
+type EventClassifier struct{}
+
+func (ec EventClassifier) Columns() []string {
+	return []string{"type", "reason"}
+}
+
+func (ec EventClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if obj.GetKind() != "Event" || (groupOf(obj) != "" && groupOf(obj) != "events.k8s.io") {
+		return nil
+	}
+
+	eventType, _, _ := unstructured.NestedString(obj.Object, "type")
+	reason, _, _ := unstructured.NestedString(obj.Object, "reason")
+	return map[string]string{
+		"type":   eventType,
+		"reason": reason,
+	}
+}
+
+type LabelClassifier struct {
+	Labels []string
+}
+
+func (lc LabelClassifier) Columns() []string {
+	return lc.Labels
+}
+
+func (lc LabelClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	labels := obj.GetLabels()
+	ret := make(map[string]string, len(lc.Labels))
+	for _, label := range lc.Labels {
+		value, ok := labels[label]
+		if !ok {
+			value = "<none>"
+		}
+		ret[label] = value
+	}
+	return ret
+}
+
+type DeploymentClassifier struct{}
+
+func (dc DeploymentClassifier) Columns() []string {
+	return []string{"status"}
+}
+
+func (dc DeploymentClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if obj.GetKind() != "Deployment" || groupOf(obj) != "apps" {
+		return nil
+	}
+
+	conditions := map[string]map[string]interface{}{}
+	items, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range items {
+		condition, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		conditions[conditionType] = condition
+	}
+	is := func(conditionType, status string) bool {
+		s, _, _ := unstructured.NestedString(conditions[conditionType], "status")
+		return s == status
+	}
+	reason, _, _ := unstructured.NestedString(conditions["Progressing"], "reason")
+
+	if is("ReplicaFailure", "True") || (is("Progressing", "False") && reason == "ProgressDeadlineExceeded") {
+		return map[string]string{"status": "failed"}
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+	available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+	if is("Available", "True") && updated >= replicas && available >= replicas {
+		return map[string]string{"status": "available"}
+	}
+	return map[string]string{"status": "progressing"}
+}
+
+type JobClassifier struct{}
+
+func (jc JobClassifier) Columns() []string {
+	return []string{"status"}
+}
+
+func (jc JobClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if obj.GetKind() != "Job" || groupOf(obj) != "batch" {
+		return nil
+	}
+	return map[string]string{"status": jobStatus(obj)}
+}
+
+func jobStatus(obj *unstructured.Unstructured) string {
+	items, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range items {
+		condition, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		status, _, _ := unstructured.NestedString(condition, "status")
+		if status != "True" {
+			continue
+		}
+		switch conditionType {
+		case "Complete":
+			return "complete"
+		case "Failed":
+			return "failed"
+		}
+	}
+	return "active"
+}
+
+type VolumeClassifier struct{}
+
+func (vc VolumeClassifier) Columns() []string {
+	return []string{"status"}
+}
+
+func (vc VolumeClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if groupOf(obj) != "" {
+		return nil
+	}
+	switch obj.GetKind() {
+	case "PersistentVolumeClaim", "PersistentVolume":
+	default:
+		return nil
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "" {
+		phase = "Pending"
+	}
+	return map[string]string{"status": phase}
+}
+
+func nodeConditions(obj *unstructured.Unstructured) map[string]string {
+	conditions := map[string]string{}
+	items, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range items {
+		condition, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		status, _, _ := unstructured.NestedString(condition, "status")
+		conditions[conditionType] = status
+	}
+	return conditions
+}
+
+type NodeClassifier struct{}
+
+func (nc NodeClassifier) Columns() []string {
+	return []string{"status"}
+}
+
+func (nc NodeClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if obj.GetKind() != "Node" || groupOf(obj) != "" {
+		return nil
+	}
+
+	// same wording as the STATUS column of kubectl get nodes.
+	status := "NotReady"
+	if nodeConditions(obj)["Ready"] == "True" {
+		status = "Ready"
+	}
+	if unschedulable, _, _ := unstructured.NestedBool(obj.Object, "spec", "unschedulable"); unschedulable {
+		status += ",SchedulingDisabled"
+	}
+	return map[string]string{"status": status}
+}
+
+type NamespaceClassifier struct{}
+
+func (nc NamespaceClassifier) Columns() []string {
+	return []string{"status"}
+}
+
+func (nc NamespaceClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if obj.GetKind() != "Namespace" || groupOf(obj) != "" {
+		return nil
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "" {
+		phase = "Active"
+	}
+	return map[string]string{"status": phase}
+}
+
+type NodeConditionClassifier struct{}
+
+func (ncc NodeConditionClassifier) Columns() []string {
+	return []string{"conditions"}
+}
+
+func (ncc NodeConditionClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if obj.GetKind() != "Node" || groupOf(obj) != "" {
+		return nil
+	}
+
+	var conditions []string
+	for conditionType, status := range nodeConditions(obj) {
+		if conditionType != "Ready" && status == "True" {
+			conditions = append(conditions, conditionType)
+		}
+	}
+	if len(conditions) == 0 {
+		return map[string]string{"conditions": "<none>"}
+	}
+	sort.Strings(conditions)
+	return map[string]string{"conditions": strings.Join(conditions, ",")}
+}
+
+type DataSizeClassifier struct{}
+
+func (dsc DataSizeClassifier) Columns() []string {
+	return []string{"size"}
+}
+
+func (dsc DataSizeClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if groupOf(obj) != "" {
+		return nil
+	}
+
+	var base64Fields []string
+	size := 0
+	switch obj.GetKind() {
+	case "ConfigMap":
+		data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+		for _, v := range data {
+			size += len(v)
+		}
+		base64Fields = []string{"binaryData"}
+	case "Secret":
+		base64Fields = []string{"data"}
+	default:
+		return nil
+	}
+	for _, field := range base64Fields {
+		data, _, _ := unstructured.NestedStringMap(obj.Object, field)
+		for _, v := range data {
+			b, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				size += len(v)
+				continue
+			}
+			size += len(b)
+		}
+	}
+
+	const kib = 1024
+	switch {
+	case size < kib:
+		return map[string]string{"size": "<1KiB"}
+	case size < 10*kib:
+		return map[string]string{"size": "<10KiB"}
+	case size < 100*kib:
+		return map[string]string{"size": "<100KiB"}
+	}
+	return map[string]string{"size": ">=100KiB"}
+}
+
+type WebhookClassifier struct{}
+
+func (wc WebhookClassifier) Columns() []string {
+	return []string{"failurePolicy", "scope", "namespaceSelector"}
+}
+
+func (wc WebhookClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if groupOf(obj) != "admissionregistration.k8s.io" {
+		return nil
+	}
+	if obj.GetKind() != "ValidatingWebhookConfiguration" && obj.GetKind() != "MutatingWebhookConfiguration" {
+		return nil
+	}
+
+	// a configuration is as risky as its riskiest webhook, unset fields take
+	// the defaults of admissionregistration.k8s.io/v1.
+	failurePolicy, selector := "Ignore", "present"
+	scopes := map[string]bool{}
+	webhooks, _, _ := unstructured.NestedSlice(obj.Object, "webhooks")
+	for _, item := range webhooks {
+		webhook, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if policy, _, _ := unstructured.NestedString(webhook, "failurePolicy"); policy != "Ignore" {
+			failurePolicy = "Fail"
+		}
+		labels, _, _ := unstructured.NestedMap(webhook, "namespaceSelector", "matchLabels")
+		expressions, _, _ := unstructured.NestedSlice(webhook, "namespaceSelector", "matchExpressions")
+		if len(labels) == 0 && len(expressions) == 0 {
+			selector = "absent"
+		}
+		rules, _, _ := unstructured.NestedSlice(webhook, "rules")
+		for _, r := range rules {
+			rule, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			scope, _, _ := unstructured.NestedString(rule, "scope")
+			if scope == "" {
+				scope = "*"
+			}
+			scopes[scope] = true
+		}
+	}
+	if len(webhooks) == 0 {
+		failurePolicy, selector = "<none>", "<none>"
+	}
+
+	scope := "<none>"
+	switch {
+	case scopes["*"] || (scopes["Cluster"] && scopes["Namespaced"]):
+		scope = "*"
+	case scopes["Cluster"]:
+		scope = "Cluster"
+	case scopes["Namespaced"]:
+		scope = "Namespaced"
+	}
+
+	return map[string]string{
+		"failurePolicy":     failurePolicy,
+		"scope":             scope,
+		"namespaceSelector": selector,
+	}
+}
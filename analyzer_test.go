@@ -0,0 +1,230 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCounterDeadlines(t *testing.T) {
+	created := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	analyzer := StaleJobsAnalyzer{After: time.Hour}
+	job := func(name string, created time.Time) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("batch/v1")
+		obj.SetKind("Job")
+		obj.SetName(name)
+		obj.SetCreationTimestamp(v1.NewTime(created))
+		return obj
+	}
+	sample := func(obj *unstructured.Unstructured) Sample {
+		return Sample{Metrics: analyzer.Analyze(obj), Deadlines: analyzer.Deadlines(obj)}
+	}
+
+	c := &Counter{}
+	old, recent := sample(job("old", created)), sample(job("recent", created.Add(time.Hour)))
+	c.add(1, old)
+	c.add(1, recent)
+
+	tests := []struct {
+		now  time.Time
+		want int
+	}{
+		{now: created.Add(30 * time.Minute), want: 0},
+		{now: created.Add(time.Hour), want: 1},
+		{now: created.Add(3 * time.Hour), want: 2},
+	}
+	for _, tt := range tests {
+		if got := c.metrics(tt.now)["staleJobs"]; got != tt.want {
+			t.Errorf("staleJobs at %s = %d, want %d", tt.now, got, tt.want)
+		}
+	}
+
+	// a stale job deleted later removes what it added, whenever it turned stale.
+	c.add(-1, old)
+	if got := c.metrics(created.Add(3 * time.Hour))["staleJobs"]; got != 1 {
+		t.Errorf("staleJobs after a delete = %d, want 1", got)
+	}
+	c.add(-1, recent)
+	if got := c.metrics(created.Add(3 * time.Hour)); !reflect.DeepEqual(got, map[string]int{"staleJobs": 0}) {
+		t.Errorf("metrics after deleting every job = %v, want staleJobs 0", got)
+	}
+}
+
+func TestAveragesOf(t *testing.T) {
+	averages := ContainersAnalyzer{}.Averages()
+	tests := []struct {
+		name   string
+		record Record
+		want   map[string]float64
+	}{
+		{
+			name:   "rounded",
+			record: Record{Count: 12, Metrics: map[string]int{"containers": 14}},
+			want:   map[string]float64{"avgContainersPerPod": 1.17},
+		},
+		{
+			name:   "subtotal",
+			record: Record{Count: 20, Metrics: map[string]int{"containers": 50}},
+			want:   map[string]float64{"avgContainersPerPod": 2.5},
+		},
+		{
+			name:   "empty namespace",
+			record: Record{Count: 0},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := averagesOf(tt.record, averages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("averagesOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeploymentClassifier(t *testing.T) {
+	condition := func(conditionType, status, reason string) interface{} {
+		return map[string]interface{}{"type": conditionType, "status": status, "reason": reason}
+	}
+	deployment := func(replicas interface{}, updated, available int64, conditions ...interface{}) *unstructured.Unstructured {
+		spec := map[string]interface{}{}
+		if replicas != nil {
+			spec["replicas"] = replicas
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"spec":       spec,
+			"status": map[string]interface{}{
+				"updatedReplicas":   updated,
+				"availableReplicas": available,
+				"conditions":        conditions,
+			},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		want map[string]string
+	}{
+		{
+			name: "available",
+			obj:  deployment(int64(3), 3, 3, condition("Available", "True", "MinimumReplicasAvailable"), condition("Progressing", "True", "NewReplicaSetAvailable")),
+			want: map[string]string{"status": "available"},
+		},
+		{
+			name: "replicas default to 1",
+			obj:  deployment(nil, 1, 1, condition("Available", "True", "MinimumReplicasAvailable")),
+			want: map[string]string{"status": "available"},
+		},
+		{
+			name: "rolling out",
+			obj:  deployment(int64(3), 1, 3, condition("Available", "True", "MinimumReplicasAvailable"), condition("Progressing", "True", "ReplicaSetUpdated")),
+			want: map[string]string{"status": "progressing"},
+		},
+		{
+			name: "progress deadline exceeded",
+			obj:  deployment(int64(3), 1, 2, condition("Available", "True", "MinimumReplicasAvailable"), condition("Progressing", "False", "ProgressDeadlineExceeded")),
+			want: map[string]string{"status": "failed"},
+		},
+		{
+			name: "replica failure",
+			obj:  deployment(int64(1), 0, 0, condition("ReplicaFailure", "True", "FailedCreate")),
+			want: map[string]string{"status": "failed"},
+		},
+		{
+			name: "not a deployment",
+			obj:  &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "apps/v1", "kind": "StatefulSet"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (DeploymentClassifier{}).Classify(tt.obj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookClassifier(t *testing.T) {
+	configuration := func(kind string, webhooks ...interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "admissionregistration.k8s.io/v1",
+			"kind":       kind,
+			"webhooks":   webhooks,
+		}}
+	}
+	webhook := func(failurePolicy string, selector map[string]interface{}, scopes ...string) interface{} {
+		w := map[string]interface{}{}
+		if failurePolicy != "" {
+			w["failurePolicy"] = failurePolicy
+		}
+		if selector != nil {
+			w["namespaceSelector"] = selector
+		}
+		var rules []interface{}
+		for _, scope := range scopes {
+			rule := map[string]interface{}{}
+			if scope != "" {
+				rule["scope"] = scope
+			}
+			rules = append(rules, rule)
+		}
+		w["rules"] = rules
+		return w
+	}
+	matchLabels := map[string]interface{}{"matchLabels": map[string]interface{}{"team": "payments"}}
+
+	tests := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			obj:  configuration("ValidatingWebhookConfiguration", webhook("", nil, "")),
+			want: map[string]string{"failurePolicy": "Fail", "scope": "*", "namespaceSelector": "absent"},
+		},
+		{
+			name: "ignored and selected",
+			obj:  configuration("MutatingWebhookConfiguration", webhook("Ignore", matchLabels, "Namespaced")),
+			want: map[string]string{"failurePolicy": "Ignore", "scope": "Namespaced", "namespaceSelector": "present"},
+		},
+		{
+			name: "riskiest webhook",
+			obj: configuration("ValidatingWebhookConfiguration",
+				webhook("Ignore", matchLabels, "Namespaced"),
+				webhook("Fail", map[string]interface{}{}, "Cluster")),
+			want: map[string]string{"failurePolicy": "Fail", "scope": "*", "namespaceSelector": "absent"},
+		},
+		{
+			name: "cluster scoped",
+			obj:  configuration("ValidatingWebhookConfiguration", webhook("Ignore", matchLabels, "Cluster")),
+			want: map[string]string{"failurePolicy": "Ignore", "scope": "Cluster", "namespaceSelector": "present"},
+		},
+		{
+			name: "no webhooks",
+			obj:  configuration("MutatingWebhookConfiguration"),
+			want: map[string]string{"failurePolicy": "<none>", "scope": "<none>", "namespaceSelector": "<none>"},
+		},
+		{
+			name: "not a webhook configuration",
+			obj:  &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "admissionregistration.k8s.io/v1", "kind": "ValidatingAdmissionPolicy"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (WebhookClassifier{}).Classify(tt.obj); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	apiSourceBuiltIn    = "built-in"
+	apiSourceCRD        = "crd"
+	apiSourceAggregated = "aggregated"
+	apiSourceUnknown    = "unknown"
+)
+
+var (
+	crdResource        = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	apiServiceResource = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+)
+
+type APIGroupRecord struct {
+	GroupVersion string `json:"groupVersion" yaml:"groupVersion"`
+	Source       string `json:"source" yaml:"source"`
+	Resources    int    `json:"resources" yaml:"resources"`
+}
+
+func builtInGroup(group string) bool {
+	return group == "" || !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
+
+func (cc *CounterController) crdGroupVersions() (map[string]bool, error) {
+	list, err := cc.dynamicClient.Resource(crdResource).List(cc.ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	groupVersions := map[string]bool{}
+	for _, item := range list.Items {
+		group, _, _ := unstructured.NestedString(item.Object, "spec", "group")
+		versions, _, _ := unstructured.NestedSlice(item.Object, "spec", "versions")
+		for _, version := range versions {
+			v, ok := version.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if served, _ := v["served"].(bool); !served {
+				continue
+			}
+			name, _ := v["name"].(string)
+			groupVersions[group+"/"+name] = true
+		}
+	}
+	return groupVersions, nil
+}
+
+// aggregatedGroupVersions are the group versions of the APIServices backed by
+// a service, the local ones are built-in or CRDs.
+func (cc *CounterController) aggregatedGroupVersions() (map[string]bool, error) {
+	list, err := cc.dynamicClient.Resource(apiServiceResource).List(cc.ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	groupVersions := map[string]bool{}
+	for _, item := range list.Items {
+		if service, _, _ := unstructured.NestedMap(item.Object, "spec", "service"); service == nil {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(item.Object, "spec", "group")
+		version, _, _ := unstructured.NestedString(item.Object, "spec", "version")
+		groupVersions[schema.GroupVersion{Group: group, Version: version}.String()] = true
+	}
+	return groupVersions, nil
+}
+
+func (cc *CounterController) APIGroups(order string) ([]APIGroupRecord, error) {
+	_, resources, err := cc.discoveryClient.ServerGroupsAndResources()
+	if err := cc.discoveryFailed(err, len(resources)); err != nil {
+		return nil, err
+	}
+
+	// non-admins are often forbidden to list CRDs, the groups which are neither
+	// built-in nor aggregated are then of an unknown source.
+	crds, err := cc.crdGroupVersions()
+	if err != nil {
+		logger.Error("list customresourcedefinitions failed", err)
+		fmt.Fprintf(os.Stderr, "[Warning] Failed to list CustomResourceDefinitions, the groups which aren't built-in or aggregated are of an unknown source, error: %v\n", err)
+		cc.warn("CRDsUnavailable", "failed to list CustomResourceDefinitions, the groups which aren't built-in or aggregated are of an unknown source: %v", err)
+	}
+	// aggregated groups like metrics.k8s.io look built-in by their name.
+	aggregated, err := cc.aggregatedGroupVersions()
+	if err != nil {
+		logger.Error("list apiservices failed", err)
+	}
+
+	records := make([]APIGroupRecord, 0, len(resources))
+	for _, resource := range resources {
+		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		n := 0
+		for _, r := range resource.APIResources {
+			if !strings.Contains(r.Name, "/") {
+				n++
+			}
+		}
+
+		source := apiSourceAggregated
+		switch {
+		case crds[resource.GroupVersion]:
+			source = apiSourceCRD
+		case aggregated[resource.GroupVersion]:
+			source = apiSourceAggregated
+		case builtInGroup(gv.Group):
+			source = apiSourceBuiltIn
+		case crds == nil:
+			source = apiSourceUnknown
+		}
+		records = append(records, APIGroupRecord{GroupVersion: resource.GroupVersion, Source: source, Resources: n})
+	}
+
+	desc := isDesc(order)
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Resources != records[j].Resources {
+			if desc {
+				return records[i].Resources > records[j].Resources
+			}
+			return records[i].Resources < records[j].Resources
+		}
+		return records[i].GroupVersion < records[j].GroupVersion
+	})
+	return records, nil
+}
+
+func (cc *CounterController) apiGroupsTableRender(w io.Writer, records []APIGroupRecord) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"GroupVersion", "Source", "Resources"})
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetRowLine(true)
+
+	totals := map[string]int{}
+	for _, record := range records {
+		totals[record.Source] += record.Resources
+		table.Append([]string{record.GroupVersion, record.Source, humanizeCount(record.Resources, cc.humanize)})
+	}
+
+	var footer []string
+	total := 0
+	for _, source := range []string{apiSourceBuiltIn, apiSourceCRD, apiSourceAggregated, apiSourceUnknown} {
+		if source == apiSourceUnknown && totals[source] == 0 {
+			continue
+		}
+		footer = append(footer, fmt.Sprintf("%s: %s", source, humanizeCount(totals[source], cc.humanize)))
+		total += totals[source]
+	}
+	table.SetFooter([]string{"Total", strings.Join(footer, ", "), humanizeCount(total, cc.humanize)})
+	table.Render()
+}
+
+func (cc *CounterController) RenderAPIGroups(order, output string) {
+	records, err := cc.APIGroups(order)
+	if err != nil {
+		logger.Error("discover resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to discover resources, error: %v", err)
+		exit(exitCode(err))
+	}
+	cc.escalateWarnings(output)
+
+	envelope := newEnvelope("APIGroupList", records)
+	envelope.Warnings = cc.warnings
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, envelope)
+	case "yaml", "y":
+		cc.yamlRender(&buf, envelope)
+	default:
+		cc.apiGroupsTableRender(&buf, records)
+	}
+	cc.write(buf.Bytes())
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func apiGroupsController(crdsForbidden bool) *CounterController {
+	resources := func(groupVersion string, names ...string) *v1.APIResourceList {
+		list := &v1.APIResourceList{GroupVersion: groupVersion}
+		for _, name := range names {
+			list.APIResources = append(list.APIResources, v1.APIResource{Name: name})
+		}
+		return list
+	}
+	discovery := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: []*v1.APIResourceList{
+		resources("v1", "pods", "pods/log", "services"),
+		resources("apps/v1", "deployments"),
+		resources("metrics.k8s.io/v1beta1", "pods", "nodes"),
+		resources("stable.example.com/v1", "crontabs"),
+		resources("other.example.com/v1", "widgets"),
+	}}}
+
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "crontabs.stable.example.com"},
+		"spec": map[string]interface{}{
+			"group":    "stable.example.com",
+			"versions": []interface{}{map[string]interface{}{"name": "v1", "served": true}},
+		},
+	}}
+	apiService := func(name, group, version string, service bool) *unstructured.Unstructured {
+		spec := map[string]interface{}{"group": group, "version": version}
+		if service {
+			spec["service"] = map[string]interface{}{"name": "metrics-server", "namespace": "kube-system"}
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apiregistration.k8s.io/v1",
+			"kind":       "APIService",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec":       spec,
+		}}
+	}
+
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			crdResource:        "CustomResourceDefinitionList",
+			apiServiceResource: "APIServiceList",
+		},
+		crd,
+		apiService("v1.apps", "apps", "v1", false),
+		apiService("v1beta1.metrics.k8s.io", "metrics.k8s.io", "v1beta1", true),
+	)
+	if crdsForbidden {
+		dynamicClient.PrependReactor("list", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(crdResource.GroupResource(), "", nil)
+		})
+	}
+
+	return &CounterController{
+		ctx:             context.Background(),
+		discoveryClient: memory.NewMemCacheClient(discovery),
+		dynamicClient:   dynamicClient,
+	}
+}
+
+func TestAPIGroups(t *testing.T) {
+	tests := []struct {
+		name          string
+		crdsForbidden bool
+		want          []APIGroupRecord
+		wantWarnings  []string
+	}{
+		{
+			name: "crds listed",
+			want: []APIGroupRecord{
+				{GroupVersion: "apps/v1", Source: apiSourceBuiltIn, Resources: 1},
+				{GroupVersion: "other.example.com/v1", Source: apiSourceAggregated, Resources: 1},
+				{GroupVersion: "stable.example.com/v1", Source: apiSourceCRD, Resources: 1},
+				{GroupVersion: "metrics.k8s.io/v1beta1", Source: apiSourceAggregated, Resources: 2},
+				{GroupVersion: "v1", Source: apiSourceBuiltIn, Resources: 2},
+			},
+		},
+		{
+			name:          "crds forbidden",
+			crdsForbidden: true,
+			want: []APIGroupRecord{
+				{GroupVersion: "apps/v1", Source: apiSourceBuiltIn, Resources: 1},
+				{GroupVersion: "other.example.com/v1", Source: apiSourceUnknown, Resources: 1},
+				{GroupVersion: "stable.example.com/v1", Source: apiSourceUnknown, Resources: 1},
+				{GroupVersion: "metrics.k8s.io/v1beta1", Source: apiSourceAggregated, Resources: 2},
+				{GroupVersion: "v1", Source: apiSourceBuiltIn, Resources: 2},
+			},
+			wantWarnings: []string{"CRDsUnavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := apiGroupsController(tt.crdsForbidden)
+			got, err := cc.APIGroups("asc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("APIGroups() = %v, want %v", got, tt.want)
+			}
+
+			var reasons []string
+			for _, warning := range cc.warnings {
+				reasons = append(reasons, warning.Reason)
+			}
+			if !reflect.DeepEqual(reasons, tt.wantWarnings) {
+				t.Errorf("APIGroups() warned %v, want %v", reasons, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type Baseline struct {
+	Tolerance string         `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+	Items     []BaselineItem `json:"items" yaml:"items"`
+}
+
+type BaselineItem struct {
+	Namespace    string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	GroupVersion string `json:"groupVersion,omitempty" yaml:"groupVersion,omitempty"`
+	Kind         string `json:"kind" yaml:"kind"`
+	Count        int    `json:"count" yaml:"count"`
+	Tolerance    string `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+}
+
+type Drift struct {
+	Namespace    string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	GroupVersion string `json:"groupVersion,omitempty" yaml:"groupVersion,omitempty"`
+	Kind         string `json:"kind" yaml:"kind"`
+	Expected     int    `json:"expected" yaml:"expected"`
+	Actual       int    `json:"actual" yaml:"actual"`
+	Tolerance    string `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+	Drifted      bool   `json:"drifted" yaml:"drifted"`
+}
+
+func loadBaseline(path string) (*Baseline, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := &Baseline{}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		err = json.Unmarshal(b, baseline)
+	} else {
+		err = yaml.Unmarshal(b, baseline)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := parseTolerance(baseline.Tolerance, 0); err != nil {
+		return nil, err
+	}
+	for _, item := range baseline.Items {
+		if item.Kind == "" {
+			return nil, fmt.Errorf("kind of baseline items is required")
+		}
+		if _, err := parseTolerance(item.Tolerance, 0); err != nil {
+			return nil, err
+		}
+	}
+	return baseline, nil
+}
+
+func parseTolerance(s string, expected int) (float64, error) {
+	// tolerances are either absolute like '3' or relative to expected like '10%'.
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || percent < 0 {
+			return 0, fmt.Errorf("invalid tolerance '%s'", s)
+		}
+		return float64(expected) * percent / 100, nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid tolerance '%s'", s)
+	}
+	return n, nil
+}
+
+func (b *Baseline) kinds() string {
+	var kinds []string
+	seen := map[string]bool{}
+	for _, item := range b.Items {
+		kind := strings.ToLower(item.Kind)
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return strings.Join(kinds, ",")
+}
+
+func (b *Baseline) drifts(records []Record) []Drift {
+	drifts := make([]Drift, 0, len(b.Items))
+	for _, item := range b.Items {
+		actual := 0
+		for _, record := range records {
+			if !strings.EqualFold(record.Kind, item.Kind) {
+				continue
+			}
+			if item.GroupVersion != "" && record.GroupVersion != item.GroupVersion {
+				continue
+			}
+			if item.Namespace != "" && record.Namespace != item.Namespace {
+				continue
+			}
+			actual += record.Count
+		}
+
+		tolerance := item.Tolerance
+		if tolerance == "" {
+			tolerance = b.Tolerance
+		}
+		allowed, _ := parseTolerance(tolerance, item.Count)
+		drifts = append(drifts, Drift{
+			Namespace:    item.Namespace,
+			GroupVersion: item.GroupVersion,
+			Kind:         item.Kind,
+			Expected:     item.Count,
+			Actual:       actual,
+			Tolerance:    tolerance,
+			Drifted:      math.Abs(float64(actual-item.Count)) > allowed,
+		})
+	}
+	return drifts
+}
+
+func (cc *CounterController) driftTableRender(w io.Writer, drifts []Drift) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"Namespace", "GroupVersion", "Kind", "Expected", "Actual", "Tolerance", "Status"})
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+
+	for _, drift := range drifts {
+		status := "ok"
+		if drift.Drifted {
+			status = fmt.Sprintf("drift (%+d)", drift.Actual-drift.Expected)
+		}
+		table.Append([]string{
+			drift.Namespace,
+			drift.GroupVersion,
+			drift.Kind,
+			humanizeCount(drift.Expected, cc.humanize),
+			humanizeCount(drift.Actual, cc.humanize),
+			drift.Tolerance,
+			status,
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderBaseline(path, kinds, output string) {
+	baseline, err := loadBaseline(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to load baseline %s, error: %v", path, err)
+		exit(exitFailure)
+	}
+	if kinds == "" {
+		kinds = baseline.kinds()
+	}
+
+	// items without a namespace sum the counts of every namespace, so records
+	// are never aggregated here.
+	records, err := cc.Records(kinds, "asc", false)
+	if err != nil {
+		logger.Error("list resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	drifts := baseline.drifts(records)
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("DriftList", drifts))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("DriftList", drifts))
+	default:
+		cc.driftTableRender(&buf, drifts)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+
+	var deviated []string
+	for _, drift := range drifts {
+		if drift.Drifted {
+			name := drift.Kind
+			if drift.Namespace != "" {
+				name = drift.Namespace + "/" + name
+			}
+			deviated = append(deviated, fmt.Sprintf("%s (expected %d, actual %d)", name, drift.Expected, drift.Actual))
+		}
+	}
+	if len(deviated) > 0 {
+		fmt.Fprintf(os.Stderr, "[Oh...] Counts drifted from the baseline: %s\n", strings.Join(deviated, ", "))
+		exit(exitDrift)
+	}
+}
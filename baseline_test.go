@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestParseTolerance(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected int
+		want     float64
+		wantErr  bool
+	}{
+		{s: "", expected: 100, want: 0},
+		{s: "3", expected: 100, want: 3},
+		{s: " 2.5 ", expected: 100, want: 2.5},
+		{s: "10%", expected: 200, want: 20},
+		{s: "0%", expected: 200, want: 0},
+		{s: "50%", expected: 0, want: 0},
+		{s: "-1", expected: 100, wantErr: true},
+		{s: "-5%", expected: 100, wantErr: true},
+		{s: "ten", expected: 100, wantErr: true},
+		{s: "%", expected: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseTolerance(tt.s, tt.expected)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTolerance(%q, %d) error = %v, wantErr %v", tt.s, tt.expected, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTolerance(%q, %d) = %v, want %v", tt.s, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
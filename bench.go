@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+)
+
+type BenchResult struct {
+	Strategy        string  `json:"strategy" yaml:"strategy"`
+	Objects         int     `json:"objects" yaml:"objects"`
+	DurationSeconds float64 `json:"durationSeconds" yaml:"durationSeconds"`
+	// peak of the heap in use above the one before the run.
+	PeakMemoryBytes uint64 `json:"peakMemoryBytes" yaml:"peakMemoryBytes"`
+	AllocatedBytes  uint64 `json:"allocatedBytes" yaml:"allocatedBytes"`
+	Requests        int    `json:"requests" yaml:"requests"`
+	Error           string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type benchStrategy struct {
+	name  string
+	count func(cc *CounterController, kinds string) (int, error)
+}
+
+var benchStrategies = []benchStrategy{
+	{name: "informer", count: benchInformer},
+	{name: "paged-list", count: benchPagedList},
+	{name: "metadata", count: benchMetadata},
+	{name: "fast", count: benchFast},
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench <kinds>",
+	Short: "Count the kinds with every counting strategy and compare their wall time, memory and requests.",
+	Example: `  # find out how expensive counting the largest kinds of the cluster is.
+  kubectl count bench pods,events,secrets`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output-format")
+		kinds := mustKinds(cmd, args)
+
+		results := make([]BenchResult, 0, len(benchStrategies))
+		var ctr *CounterController
+		for _, strategy := range benchStrategies {
+			// every strategy starts with a controller of its own, nothing is shared but the discovery cache.
+			ctr = mustController(cmd)
+			result := ctr.bench(strategy, kinds)
+			if ctr.partial() {
+				exit(exitInterrupted)
+			}
+			results = append(results, result)
+		}
+		ctr.RenderBench(results, format)
+		exit(0)
+	},
+}
+
+func (cc *CounterController) bench(strategy benchStrategy, kinds string) BenchResult {
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	done := make(chan struct{})
+	peakCh := make(chan uint64)
+	go func() {
+		peakCh <- heapPeak(done)
+	}()
+
+	started := time.Now()
+	objects, err := strategy.count(cc, kinds)
+	wallTime := time.Since(started)
+	close(done)
+	peak := <-peakCh
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	result := BenchResult{
+		Strategy:        strategy.name,
+		Objects:         objects,
+		DurationSeconds: wallTime.Seconds(),
+		AllocatedBytes:  after.TotalAlloc - before.TotalAlloc,
+		Requests:        cc.retryStats.Requests(),
+	}
+	if peak > before.HeapInuse {
+		result.PeakMemoryBytes = peak - before.HeapInuse
+	}
+	if err != nil {
+		logger.Error("bench failed", err, "strategy", strategy.name)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func heapPeak(done <-chan struct{}) uint64 {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var peak uint64
+	for {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.HeapInuse > peak {
+			peak = m.HeapInuse
+		}
+		select {
+		case <-done:
+			return peak
+		case <-ticker.C:
+		}
+	}
+}
+
+func benchInformer(cc *CounterController, kinds string) (int, error) {
+	idMap, err := cc.list(kinds)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, record := range idMap.GetRecords("", true) {
+		total += record.Count
+	}
+	return total, nil
+}
+
+func (cc *CounterController) benchResources(kinds string) ([]APIResourceGV, error) {
+	resolutions, err := cc.resolve(kinds)
+	if err != nil {
+		return nil, err
+	}
+	apiResources, err := cc.getApiResources()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []APIResourceGV
+	seen := map[string]bool{}
+	for _, resolution := range resolutions {
+		if resolution.Status == resolutionUnmatched {
+			return nil, fmt.Errorf("%s doesn't match any resource which can be listed and watched", resolution.Input)
+		}
+		for _, ar := range apiResources[resolution.Input] {
+			if !seen[ar.ID()] {
+				seen[ar.ID()] = true
+				ret = append(ret, ar)
+			}
+		}
+	}
+	return ret, nil
+}
+
+func benchPagedList(cc *CounterController, kinds string) (int, error) {
+	ars, err := cc.benchResources(kinds)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, ar := range ars {
+		_, err := cc.listPages(cc.resourceInterface(ar), func(*unstructured.Unstructured) { total++ })
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+func (cc *CounterController) metadataResource(ar APIResourceGV) metadata.ResourceInterface {
+	gvr := schema.GroupVersionResource{Group: ar.resource.Group, Version: ar.resource.Version, Resource: ar.resource.Name}
+	if ar.resource.Namespaced {
+		return cc.metadataClient.Resource(gvr).Namespace(cc.namespace)
+	}
+	return cc.metadataClient.Resource(gvr)
+}
+
+// metadata lists pages of PartialObjectMetadata, the apiserver leaves out the spec and status.
+func benchMetadata(cc *CounterController, kinds string) (int, error) {
+	ars, err := cc.benchResources(kinds)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, ar := range ars {
+		opts := v1.ListOptions{Limit: pageSize}
+		for {
+			list, err := cc.metadataResource(ar).List(cc.ctx, opts)
+			if err != nil {
+				return 0, err
+			}
+			total += len(list.Items)
+			if list.Continue == "" {
+				break
+			}
+			opts.Continue = list.Continue
+		}
+	}
+	return total, nil
+}
+
+// fast asks for a single object and adds up the remainingItemCount of the list,
+// the apiserver leaves it out with selectors, the pages are listed then.
+func benchFast(cc *CounterController, kinds string) (int, error) {
+	ars, err := cc.benchResources(kinds)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, ar := range ars {
+		opts := v1.ListOptions{Limit: 1}
+		for {
+			list, err := cc.metadataResource(ar).List(cc.ctx, opts)
+			if err != nil {
+				return 0, err
+			}
+			total += len(list.Items)
+			if list.RemainingItemCount != nil {
+				total += int(*list.RemainingItemCount)
+				break
+			}
+			if list.Continue == "" {
+				break
+			}
+			opts = v1.ListOptions{Limit: pageSize, Continue: list.Continue}
+		}
+	}
+	return total, nil
+}
+
+func (cc *CounterController) benchTableRender(w io.Writer, results []BenchResult) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"Strategy", "Objects", "Wall Time", "Peak Memory", "Allocated", "Requests"})
+	table.SetAutoFormatHeaders(false)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT})
+
+	for _, result := range results {
+		if result.Error != "" {
+			table.Append([]string{result.Strategy, "failed: " + result.Error, "", "", "", ""})
+			continue
+		}
+		table.Append([]string{
+			result.Strategy,
+			humanizeCount(result.Objects, cc.humanize),
+			time.Duration(result.DurationSeconds * float64(time.Second)).Round(time.Millisecond).String(),
+			humanizeBytes(result.PeakMemoryBytes),
+			humanizeBytes(result.AllocatedBytes),
+			fmt.Sprintf("%d", result.Requests),
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderBench(results []BenchResult, output string) {
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("BenchResultList", results))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("BenchResultList", results))
+	default:
+		cc.benchTableRender(&buf, results)
+	}
+	cc.write(buf.Bytes())
+}
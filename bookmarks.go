@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// the apiserver bookmarks a watch 2s before its timeout, once the events
+	// since the resourceVersion were sent.
+	bookmarkWatchTimeout = 3
+	// kinds with more objects are relisted on every run rather than keeping
+	// a bookmark file of hundreds of MBs.
+	bookmarkMaxObjects = 100000
+)
+
+type Bookmarks struct {
+	Kinds map[string]*KindBookmark `json:"kinds"`
+}
+
+// KindBookmark keeps the sample of every object, a watch event only carries
+// the object after the change while the sample it replaces has to be taken
+// out of the counts.
+type KindBookmark struct {
+	ResourceVersion string            `json:"resourceVersion"`
+	Objects         map[string]Sample `json:"objects"`
+}
+
+func loadBookmarks(path string) *Bookmarks {
+	bookmarks := &Bookmarks{Kinds: map[string]*KindBookmark{}}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("load bookmarks failed", err, "path", path)
+		}
+		return bookmarks
+	}
+	if err := json.Unmarshal(b, bookmarks); err != nil {
+		logger.Error("load bookmarks failed", err, "path", path)
+		return &Bookmarks{Kinds: map[string]*KindBookmark{}}
+	}
+	if bookmarks.Kinds == nil {
+		bookmarks.Kinds = map[string]*KindBookmark{}
+	}
+	return bookmarks
+}
+
+func saveBookmarks(path string, bookmarks *Bookmarks) {
+	b, err := json.Marshal(bookmarks)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = writeFileAtomic(path, b)
+	}
+	if err != nil {
+		logger.Error("save bookmarks failed", err, "path", path)
+	}
+}
+
+func (cc *CounterController) observeBookmark(id string, kb *KindBookmark, obj *unstructured.Unstructured, deleted bool) {
+	key, _ := cache.MetaNamespaceKeyFunc(obj)
+	delete(kb.Objects, key)
+	if !deleted && cc.match(obj) {
+		kb.Objects[key] = cc.analyze(obj)
+		cc.timing.ObserveObject(id)
+	}
+}
+
+func (cc *CounterController) relist(id string, ri dynamic.ResourceInterface, kb *KindBookmark) error {
+	kb.Objects = map[string]Sample{}
+	resourceVersion, err := cc.listPages(ri, func(obj *unstructured.Unstructured) {
+		cc.observeBookmark(id, kb, obj, false)
+	})
+	if err != nil {
+		return err
+	}
+	kb.ResourceVersion = resourceVersion
+	return nil
+}
+
+func (cc *CounterController) resume(id string, ri dynamic.ResourceInterface, kb *KindBookmark) error {
+	if kb.ResourceVersion == "" || kb.Objects == nil {
+		return cc.relist(id, ri, kb)
+	}
+
+	timeout := int64(bookmarkWatchTimeout)
+	w, err := ri.Watch(cc.ctx, v1.ListOptions{ResourceVersion: kb.ResourceVersion, AllowWatchBookmarks: true, TimeoutSeconds: &timeout})
+	if apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+		logger.Info("bookmark expired", "id", id, "resourceVersion", kb.ResourceVersion)
+		return cc.relist(id, ri, kb)
+	}
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-cc.ctx.Done():
+			return errInterrupted
+		case event, ok := <-w.ResultChan():
+			// apiservers which don't bookmark, e.g. aggregated ones, can't
+			// tell the watch caught up.
+			if !ok {
+				logger.Info("watch closed without a bookmark", "id", id, "resourceVersion", kb.ResourceVersion)
+				return cc.relist(id, ri, kb)
+			}
+			if event.Type == watch.Error {
+				err := apierrors.FromObject(event.Object)
+				if apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+					logger.Info("bookmark expired", "id", id, "resourceVersion", kb.ResourceVersion)
+					return cc.relist(id, ri, kb)
+				}
+				return err
+			}
+			o, ok := event.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			kb.ResourceVersion = o.GetResourceVersion()
+			if event.Type == watch.Bookmark {
+				return nil
+			}
+			cc.observeBookmark(id, kb, o, event.Type == watch.Deleted)
+		}
+	}
+}
+
+func (cc *CounterController) incremental(s string) (*IDMap, error) {
+	defer cc.cancel()
+
+	kinds := cc.sanitizeKinds(s)
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("invalid input kind name: '%s'", s)
+	}
+	discoveryStarted := time.Now()
+	apiResources, err := cc.getApiResources()
+	if err != nil {
+		return nil, err
+	}
+	cc.timing.ObserveDiscovery(time.Since(discoveryStarted))
+
+	bookmarks := loadBookmarks(cc.bookmarks)
+	idMap := NewIDMap()
+	resources := map[string]APIResourceGV{}
+	for _, kind := range kinds {
+		for _, ar := range apiResources[kind] {
+			resources[ar.ID()] = ar
+			idMap.AddID(ar.ID(), ar.resource.Namespaced)
+			if bookmarks.Kinds[ar.ID()] == nil {
+				bookmarks.Kinds[ar.ID()] = &KindBookmark{}
+			}
+		}
+	}
+	if len(resources) == 0 {
+		return nil, errors.New("no available informers found")
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var errs []error
+	for id, ar := range resources {
+		wg.Add(1)
+		go func(id string, ar APIResourceGV) {
+			defer wg.Done()
+			started := time.Now()
+			kb := bookmarks.Kinds[id]
+			if err := cc.resume(id, cc.resourceInterface(ar), kb); err != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+				return
+			}
+			cc.timing.ObserveSync(id, time.Since(started))
+			logger.Info("bookmark resumed", "id", id, "resourceVersion", kb.ResourceVersion, "objects", len(kb.Objects), "duration", time.Since(started).String())
+		}(id, ar)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&cc.interrupted) == 1 {
+		return idMap, errInterrupted
+	}
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	for id := range resources {
+		kb := bookmarks.Kinds[id]
+		for key, sample := range kb.Objects {
+			namespace, _, _ := cache.SplitMetaNamespaceKey(key)
+			idMap.Add(id, namespace, sample)
+		}
+		if len(kb.Objects) > bookmarkMaxObjects {
+			logger.Info("bookmark dropped", "id", id, "objects", len(kb.Objects), "max", bookmarkMaxObjects)
+			bookmarks.Kinds[id] = &KindBookmark{}
+		}
+	}
+	saveBookmarks(cc.bookmarks, bookmarks)
+	return idMap, nil
+}
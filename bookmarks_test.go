@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestResume(t *testing.T) {
+	podResource := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	pod := func(name, resourceVersion string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("Pod")
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		obj.SetResourceVersion(resourceVersion)
+		return obj
+	}
+	bookmark := func(resourceVersion string) watch.Event {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("Pod")
+		obj.SetResourceVersion(resourceVersion)
+		return watch.Event{Type: watch.Bookmark, Object: obj}
+	}
+	expired := watch.Event{Type: watch.Error, Object: &apierrors.NewResourceExpired("too old resource version").ErrStatus}
+
+	tests := []struct {
+		name                string
+		bookmark            *KindBookmark
+		events              []watch.Event
+		wantObjects         []string
+		wantResourceVersion string
+	}{
+		{
+			name:        "no bookmark",
+			bookmark:    &KindBookmark{},
+			wantObjects: []string{"default/a", "default/b"},
+		},
+		{
+			name:     "changes applied until the bookmark",
+			bookmark: &KindBookmark{ResourceVersion: "10", Objects: map[string]Sample{"default/a": {}, "default/old": {}}},
+			events: []watch.Event{
+				{Type: watch.Added, Object: pod("new", "11")},
+				{Type: watch.Deleted, Object: pod("old", "12")},
+				bookmark("20"),
+				{Type: watch.Added, Object: pod("late", "21")},
+			},
+			wantObjects:         []string{"default/a", "default/new"},
+			wantResourceVersion: "20",
+		},
+		{
+			name:        "closed without a bookmark",
+			bookmark:    &KindBookmark{ResourceVersion: "10", Objects: map[string]Sample{"default/old": {}}},
+			events:      []watch.Event{{Type: watch.Added, Object: pod("new", "11")}},
+			wantObjects: []string{"default/a", "default/b"},
+		},
+		{
+			name:        "expired",
+			bookmark:    &KindBookmark{ResourceVersion: "10", Objects: map[string]Sample{"default/old": {}}},
+			events:      []watch.Event{expired},
+			wantObjects: []string{"default/a", "default/b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{podResource: "PodList"},
+				pod("a", "1"), pod("b", "2"))
+			w := watch.NewFakeWithChanSize(len(tt.events), false)
+			for _, event := range tt.events {
+				w.Action(event.Type, event.Object)
+			}
+			w.Stop()
+
+			var resourceVersion string
+			client.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+				resourceVersion = action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion
+				return true, w, nil
+			})
+
+			cc := &CounterController{ctx: context.Background(), timing: NewTiming()}
+			if err := cc.resume("Pod+v1", client.Resource(podResource), tt.bookmark); err != nil {
+				t.Fatal(err)
+			}
+
+			var objects []string
+			for key := range tt.bookmark.Objects {
+				objects = append(objects, key)
+			}
+			sort.Strings(objects)
+			if !reflect.DeepEqual(objects, tt.wantObjects) {
+				t.Errorf("resume() objects = %v, want %v", objects, tt.wantObjects)
+			}
+			if tt.wantResourceVersion != "" && tt.bookmark.ResourceVersion != tt.wantResourceVersion {
+				t.Errorf("resume() resourceVersion = %q, want %q", tt.bookmark.ResourceVersion, tt.wantResourceVersion)
+			}
+			if len(tt.events) > 0 && resourceVersion != "10" {
+				t.Errorf("resume() watched from resourceVersion %q, want 10", resourceVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/util/homedir"
+)
+
+// flags which only change how the counts are printed share a cache entry.
+var uncachedFlags = map[string]bool{
+	"cached":        true,
+	"incremental":   true,
+	"no-cache":      true,
+	"cache-ttl":     true,
+	"output-format": true,
+	"output-file":   true,
+	"humanize":      true,
+	"no-pager":      true,
+	"show-timing":   true,
+	"log-format":    true,
+	"log-file":      true,
+	"v":             true,
+}
+
+func cachePath(cmd *cobra.Command, dir, kinds string) (string, error) {
+	config, err := cf.ToRESTConfig()
+	if err != nil {
+		return "", err
+	}
+	namespace, _, err := cf.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{config.Host, currentContext(), namespace, kinds}
+	var flags []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if !uncachedFlags[f.Name] {
+			flags = append(flags, f.Name+"="+f.Value.String())
+		}
+	})
+	sort.Strings(flags)
+	parts = append(parts, flags...)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+
+	cacheDir := filepath.Join(homedir.HomeDir(), ".kube", "cache")
+	if cf.CacheDir != nil && *cf.CacheDir != "" {
+		cacheDir = *cf.CacheDir
+	}
+	return filepath.Join(discoveryCacheDir(filepath.Join(cacheDir, dir), config.Host), hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func loadCountCache(path string, ttl time.Duration) ([]Record, bool) {
+	snapshot, err := loadSnapshot(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("load count cache failed", err, "path", path)
+		}
+		return nil, false
+	}
+	if time.Since(snapshot.CollectedAt) > ttl {
+		return nil, false
+	}
+	logger.Info("counts served from cache", "path", path, "collectedAt", snapshot.CollectedAt)
+	fmt.Fprintf(os.Stderr, "[Cache] counts collected %s ago\n", age(snapshot.CollectedAt))
+	return snapshot.Items, true
+}
+
+func saveCountCache(path string, records []Record) {
+	b, err := json.Marshal(Snapshot{
+		SchemaVersion: schemaVersion,
+		Kind:          "Snapshot",
+		CollectedAt:   time.Now().UTC(),
+		Context:       currentContext(),
+		Items:         records,
+	})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = writeFileAtomic(path, b)
+	}
+	if err != nil {
+		logger.Error("save count cache failed", err, "path", path)
+	}
+}
+
+func (cc *CounterController) RenderCached(cmd *cobra.Command, kinds, order, output string, allNamespace bool, ttl time.Duration) {
+	path, err := cachePath(cmd, "counts", kinds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to load kubeconfig, error: %v", err)
+		exit(exitFailure)
+	}
+	if noCache, _ := cmd.Flags().GetBool("no-cache"); !noCache {
+		if records, ok := loadCountCache(path, ttl); ok {
+			if cc.thresholds != nil {
+				cc.breaches = cc.thresholds.breaches(records)
+			}
+			cc.output(records, output)
+			cc.enforceThresholds()
+			return
+		}
+	}
+
+	records, err := cc.Records(kinds, order, allNamespace)
+	if err != nil {
+		logger.Error("list resources failed", err)
+		cc.fail(output, "Failed to list resources", err)
+	}
+	cc.escalateWarnings(output)
+	// partial counts of an interrupted run must not be served later.
+	if atomic.LoadInt32(&cc.interrupted) == 0 {
+		saveCountCache(path, records)
+	}
+	cc.output(records, output)
+	cc.enforceThresholds()
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+type ChurnRecord struct {
+	Namespace        string  `json:"namespace" yaml:"namespace"`
+	GroupVersion     string  `json:"groupVersion" yaml:"groupVersion"`
+	Kind             string  `json:"kind" yaml:"kind"`
+	AddsPerMinute    float64 `json:"addsPerMinute" yaml:"addsPerMinute"`
+	UpdatesPerMinute float64 `json:"updatesPerMinute" yaml:"updatesPerMinute"`
+	DeletesPerMinute float64 `json:"deletesPerMinute" yaml:"deletesPerMinute"`
+}
+
+func (cr ChurnRecord) total() float64 {
+	return cr.AddsPerMinute + cr.UpdatesPerMinute + cr.DeletesPerMinute
+}
+
+type churnCounter struct {
+	adds    int
+	updates int
+	deletes int
+}
+
+type ChurnMap struct {
+	lock    sync.Mutex
+	m       map[string]map[string]*churnCounter
+	started time.Time
+	stopped time.Time
+}
+
+func NewChurnMap() *ChurnMap {
+	return &ChurnMap{
+		m: map[string]map[string]*churnCounter{},
+	}
+}
+
+func (cm *ChurnMap) Start() {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
+	cm.started = time.Now()
+}
+
+func (cm *ChurnMap) Stop() {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
+	cm.stopped = time.Now()
+}
+
+func (cm *ChurnMap) observe(id string, obj interface{}, fn func(c *churnCounter, o *unstructured.Unstructured) bool) {
+	o, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
+	if cm.started.IsZero() || !cm.stopped.IsZero() {
+		return
+	}
+	if _, ok := cm.m[id]; !ok {
+		cm.m[id] = map[string]*churnCounter{}
+	}
+	c, ok := cm.m[id][o.GetNamespace()]
+	if !ok {
+		c = &churnCounter{}
+	}
+	if fn(c, o) {
+		cm.m[id][o.GetNamespace()] = c
+	}
+}
+
+func (cm *ChurnMap) Handler(id string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			cm.observe(id, obj, func(c *churnCounter, o *unstructured.Unstructured) bool {
+				// informers replay the initial list as adds, only objects created
+				// after the window started are real creations.
+				if o.GetCreationTimestamp().Time.Before(cm.started.Truncate(time.Second)) {
+					return false
+				}
+				c.adds++
+				return true
+			})
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, ok := oldObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			cm.observe(id, newObj, func(c *churnCounter, o *unstructured.Unstructured) bool {
+				if old.GetResourceVersion() == o.GetResourceVersion() {
+					return false
+				}
+				c.updates++
+				return true
+			})
+		},
+		DeleteFunc: func(obj interface{}) {
+			cm.observe(id, obj, func(c *churnCounter, o *unstructured.Unstructured) bool {
+				c.deletes++
+				return true
+			})
+		},
+	}
+}
+
+func (cm *ChurnMap) GetRecords(idMap *IDMap, order string, allNamespace bool) []ChurnRecord {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	idMap.lock.Lock()
+	defer idMap.lock.Unlock()
+
+	minutes := cm.stopped.Sub(cm.started).Minutes()
+	if minutes <= 0 {
+		minutes = 1
+	}
+	perMinute := func(n int) float64 {
+		return float64(n) / minutes
+	}
+
+	records := map[string][]ChurnRecord{}
+	for _, id := range idMap.ids {
+		kind, groupVersion := idMap.KindGroupVersion(id)
+
+		namespaces := map[string]struct{}{}
+		for bucket := range idMap.m[id] {
+			namespaces[bucket.Namespace] = struct{}{}
+		}
+		for ns := range cm.m[id] {
+			namespaces[ns] = struct{}{}
+		}
+
+		total := &churnCounter{}
+		rs := make([]ChurnRecord, 0)
+		for ns := range namespaces {
+			c, ok := cm.m[id][ns]
+			if !ok {
+				c = &churnCounter{}
+			}
+			total.adds += c.adds
+			total.updates += c.updates
+			total.deletes += c.deletes
+			rs = append(rs, ChurnRecord{
+				Namespace:        ns,
+				GroupVersion:     groupVersion,
+				Kind:             kind,
+				AddsPerMinute:    perMinute(c.adds),
+				UpdatesPerMinute: perMinute(c.updates),
+				DeletesPerMinute: perMinute(c.deletes),
+			})
+		}
+
+		if allNamespace {
+			rs = []ChurnRecord{{
+				GroupVersion:     groupVersion,
+				Kind:             kind,
+				AddsPerMinute:    perMinute(total.adds),
+				UpdatesPerMinute: perMinute(total.updates),
+				DeletesPerMinute: perMinute(total.deletes),
+			}}
+		}
+		records[id] = rs
+	}
+
+	desc := isDesc(order)
+	for _, rs := range records {
+		sort.SliceStable(rs, func(i, j int) bool {
+			if rs[i].total() != rs[j].total() {
+				if desc {
+					return rs[i].total() > rs[j].total()
+				}
+				return rs[i].total() < rs[j].total()
+			}
+			if rs[i].Namespace != rs[j].Namespace {
+				return rs[i].Namespace < rs[j].Namespace
+			}
+			return rs[i].Kind < rs[j].Kind
+		})
+	}
+
+	ret := make([]ChurnRecord, 0)
+	for _, id := range idMap.ids {
+		ret = append(ret, records[id]...)
+	}
+	return ret
+}
+
+func (cc *CounterController) churn(s string, window time.Duration) (*IDMap, *ChurnMap, error) {
+	defer cc.cancel()
+
+	churnMap := NewChurnMap()
+	idMap, err := cc.sync(s, churnMap.Handler)
+	if errors.Is(err, errInterrupted) {
+		churnMap.Start()
+		churnMap.Stop()
+		return idMap, churnMap, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	churnMap.Start()
+	select {
+	case <-time.After(window):
+	case <-cc.ctx.Done():
+	}
+	churnMap.Stop()
+
+	return idMap, churnMap, nil
+}
+
+func (cc *CounterController) churnTableRender(w io.Writer, records []ChurnRecord) {
+	headers := []string{"Namespace", "GroupVersion", "Kind", "Adds/min", "Updates/min", "Deletes/min"}
+	table := cc.newTable(w)
+	table.SetHeader(headers)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+
+	for _, record := range records {
+		table.Append([]string{
+			record.Namespace,
+			record.GroupVersion,
+			record.Kind,
+			fmt.Sprintf("%.2f", record.AddsPerMinute),
+			fmt.Sprintf("%.2f", record.UpdatesPerMinute),
+			fmt.Sprintf("%.2f", record.DeletesPerMinute),
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderChurn(kinds, order, output string, allNamespace bool, window time.Duration) {
+	idMap, churnMap, err := cc.churn(kinds, window)
+	cc.summarize()
+	if err != nil {
+		logger.Error("watch resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to watch resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	records := churnMap.GetRecords(idMap, order, allNamespace)
+	if len(records) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+		records = []ChurnRecord{}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("ChurnRecordList", records))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("ChurnRecordList", records))
+	default:
+		cc.churnTableRender(&buf, records)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
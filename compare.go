@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+type Comparison struct {
+	Namespace    string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	GroupVersion string            `json:"groupVersion" yaml:"groupVersion"`
+	Kind         string            `json:"kind" yaml:"kind"`
+	Breakdown    map[string]string `json:"breakdown,omitempty" yaml:"breakdown,omitempty"`
+	Counts       map[string]int    `json:"counts" yaml:"counts"`
+	Delta        int               `json:"delta" yaml:"delta"`
+	Forbidden    []string          `json:"forbidden,omitempty" yaml:"forbidden,omitempty"`
+}
+
+var compareNamespacesCmd = &cobra.Command{
+	Use:   "compare-ns <namespace> <namespace> <kinds>",
+	Short: "Compare the resources counts of two namespaces side by side.",
+	Example: `  # find the drift between the staging and prod namespaces.
+  kubectl count compare-ns staging prod pods,deploy,cm,secret`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+			return err
+		}
+		return kindsArgs(cmd, args[2:])
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output-format")
+		namespaces := args[:2]
+		kinds := mustKinds(cmd, args[2:])
+
+		// every namespace is counted by its own controller, users allowed to
+		// list the two namespaces only can still compare them.
+		results := make([][]Record, len(namespaces))
+		errs := make([]error, len(namespaces))
+		var ctr *CounterController
+		var wg sync.WaitGroup
+		for i, namespace := range namespaces {
+			c, err := newNamespacedController(cmd, cf, namespace)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to Create Controller, error: %v", err)
+				exit(exitFailure)
+			}
+			ctr = c
+
+			wg.Add(1)
+			go func(i int, c *CounterController) {
+				defer wg.Done()
+				results[i], errs[i] = c.Records(kinds, "asc", false)
+			}(i, c)
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			if err != nil {
+				logger.Error("list resources failed", err, "namespace", namespaces[i])
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources in namespace %s, error: %v", namespaces[i], err)
+				exit(exitCode(err))
+			}
+		}
+		ctr.RenderComparison(namespaces, compareRecords(namespaces, results, false), format)
+		exit(0)
+	},
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare --contexts <context>,<context> <kinds>",
+	Short: "Compare the resources counts of clusters side by side.",
+	Example: `  # validate a migration by comparing the counts of every namespace in both clusters.
+  kubectl count compare --contexts old,new pods,deploy,svc`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		contexts, _ := cmd.Flags().GetStringSlice("contexts")
+		if len(contexts) < 2 {
+			fmt.Fprintln(os.Stderr, "[Oh...] At least two --contexts are required!")
+			exit(exitFailure)
+		}
+		order, _ := cmd.Flags().GetString("order")
+		format, _ := cmd.Flags().GetString("output-format")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+		workers, _ := cmd.Flags().GetInt("workers")
+		timeout, _ := cmd.Flags().GetDuration("cluster-timeout")
+
+		clusters := make([]Cluster, 0, len(contexts))
+		for _, context := range contexts {
+			clusters = append(clusters, Cluster{Name: context, Kubeconfig: *cf.KubeConfig, Context: context})
+		}
+		records, errs, ctr := countClusters(cmd, clusters, workers, timeout, mustKinds(cmd, args), order, allNamespace)
+		summarizeClusters(len(clusters), errs)
+		if len(errs) > 0 {
+			exit(fleetExitCode(errs))
+		}
+
+		results := make([][]Record, len(contexts))
+		for i, context := range contexts {
+			for _, record := range records {
+				if record.Cluster == context {
+					results[i] = append(results[i], record)
+				}
+			}
+		}
+		ctr.RenderComparison(contexts, compareRecords(contexts, results, true), format)
+		exit(0)
+	},
+}
+
+var compareAsCmd = &cobra.Command{
+	Use:   "compare-as --as <user> [--as-group <group>] <kinds>",
+	Short: "Compare the resources counts visible to the current identity and to an impersonated one.",
+	Example: `  # show which pods and secrets jane can and cannot see.
+  kubectl count compare-as --as jane -A pods,secrets`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if *cf.Impersonate == "" && len(*cf.ImpersonateGroup) == 0 {
+			fmt.Fprintln(os.Stderr, "[Oh...] --as or --as-group is required!")
+			exit(exitFailure)
+		}
+		format, _ := cmd.Flags().GetString("output-format")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+
+		identity := *cf.Impersonate
+		if identity == "" {
+			identity = "group " + strings.Join(*cf.ImpersonateGroup, ",")
+		}
+		names := []string{"current", identity}
+		kinds := mustKinds(cmd, args)
+
+		current := clusterConfigFlags("", "")
+		current.Context = cf.Context
+		current.Impersonate = new(string)
+		current.ImpersonateUID = new(string)
+		current.ImpersonateGroup = &[]string{}
+		impersonated := clusterConfigFlags("", "")
+		impersonated.Context = cf.Context
+
+		controllers := make([]*CounterController, 0, len(names))
+		for _, configFlags := range []*genericclioptions.ConfigFlags{current, impersonated} {
+			c, err := newController(cmd, configFlags)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to Create Controller, error: %v", err)
+				exit(exitFailure)
+			}
+			controllers = append(controllers, c)
+		}
+
+		// the impersonated identity may not be allowed to list namespaces,
+		// they're listed as the current one.
+		var namespaces []string
+		var namespacesOnce sync.Once
+		listNamespaces := func() []string {
+			namespacesOnce.Do(func() {
+				namespaces = controllers[0].namespaceNames()
+			})
+			return namespaces
+		}
+
+		results := make([][]Record, len(names))
+		forbidden := make([][]Record, len(names))
+		var wg sync.WaitGroup
+		for i, c := range controllers {
+			wg.Add(1)
+			go func(i int, c *CounterController) {
+				defer wg.Done()
+				results[i], forbidden[i] = c.visibleRecords(names[i], kinds, allNamespace, listNamespaces)
+			}(i, c)
+		}
+		wg.Wait()
+
+		controllers[0].RenderComparison(names, markForbidden(names, forbidden, compareRecords(names, results, true)), format)
+		exit(0)
+	},
+}
+
+// visibleRecords counts the kinds with paged lists, a kind the identity can't
+// list doesn't fail the others. A kind it can't list cluster-wide is counted in
+// the namespaces it may list it in, and is returned as forbidden when there is none.
+func (cc *CounterController) visibleRecords(identity, kinds string, allNamespace bool, namespaces func() []string) ([]Record, []Record) {
+	failed := func(err error) {
+		logger.Error("list resources failed", err, "identity", identity)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources as %s, error: %v", identity, err)
+		exit(exitCode(err))
+	}
+	apiResources, err := cc.getApiResources()
+	if err != nil {
+		failed(err)
+	}
+
+	idMap := NewIDMap()
+	var forbidden []Record
+	for _, kind := range cc.sanitizeKinds(kinds) {
+		ars, ok := apiResources[kind]
+		if !ok {
+			failed(fmt.Errorf("%s doesn't match any resource which can be listed and watched", kind))
+		}
+		for _, ar := range ars {
+			id := ar.ID()
+			idMap.AddID(id, ar.resource.Namespaced)
+			err := cc.countPages(id, ar, idMap)
+			if accessDenied(err) && ar.resource.Namespaced && cc.namespace == "" {
+				var visible int
+				visible, err = cc.countNamespaces(id, ar, namespaces(), idMap)
+				if err == nil {
+					logger.Info("kind visible in namespaces only", "identity", identity, "id", id, "namespaces", visible)
+					fmt.Fprintf(os.Stderr, "[Forbidden] %s can't list %s cluster-wide, counted in the %d namespaces it may list them in\n", identity, id, visible)
+				}
+			}
+			if accessDenied(err) {
+				logger.Info("kind not visible", "identity", identity, "id", id)
+				fmt.Fprintf(os.Stderr, "[Forbidden] %s can't list %s\n", identity, id)
+				k, groupVersion := splitID(id)
+				forbidden = append(forbidden, Record{GroupVersion: groupVersion, Kind: k})
+				continue
+			}
+			// the kinds counted before the interruption are still compared.
+			if err != nil && atomic.LoadInt32(&cc.interrupted) == 1 {
+				cc.summarize()
+				return idMap.GetRecords("asc", allNamespace), forbidden
+			}
+			if err != nil {
+				failed(err)
+			}
+		}
+	}
+	cc.summarize()
+	return idMap.GetRecords("asc", allNamespace), forbidden
+}
+
+func accessDenied(err error) bool {
+	return errors.Is(err, errAccessDenied) || apierrors.IsForbidden(err)
+}
+
+func (cc *CounterController) namespaceNames() []string {
+	_, items, err := cc.listResource("namespaces", "")
+	if err != nil {
+		logger.Error("list namespaces failed", err)
+		return nil
+	}
+
+	namespaces := make([]string, 0, len(items))
+	for _, item := range items {
+		namespaces = append(namespaces, item.GetName())
+	}
+	return namespaces
+}
+
+// countNamespaces counts the kind in every namespace it can be listed in,
+// errAccessDenied is returned when there is none.
+func (cc *CounterController) countNamespaces(id string, ar APIResourceGV, namespaces []string, idMap *IDMap) (int, error) {
+	gvr := schema.GroupVersionResource{Group: ar.resource.Group, Version: ar.resource.Version, Resource: ar.resource.Name}
+	paged := NewIDMap()
+	visible := 0
+	for _, namespace := range namespaces {
+		_, err := cc.listPages(cc.dynamicClient.Resource(gvr).Namespace(namespace), func(obj *unstructured.Unstructured) {
+			if cc.match(obj) {
+				paged.Add(id, obj.GetNamespace(), cc.analyze(obj))
+				cc.timing.ObserveObject(id)
+			}
+		})
+		if accessDenied(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		visible++
+	}
+	if visible == 0 {
+		return 0, errAccessDenied
+	}
+	idMap.Replace(id, paged)
+	return visible, nil
+}
+
+// markForbidden marks the comparisons of the kinds an identity can't list,
+// adding a comparison for the kinds none of the identities could count.
+func markForbidden(names []string, forbidden [][]Record, comparisons []Comparison) []Comparison {
+	for i, records := range forbidden {
+		for _, record := range records {
+			found := false
+			for j := range comparisons {
+				if comparisons[j].GroupVersion != record.GroupVersion || comparisons[j].Kind != record.Kind {
+					continue
+				}
+				comparisons[j].Forbidden = append(comparisons[j].Forbidden, names[i])
+				found = true
+			}
+			if found {
+				continue
+			}
+
+			comparison := Comparison{GroupVersion: record.GroupVersion, Kind: record.Kind, Counts: map[string]int{}, Forbidden: []string{names[i]}}
+			for _, name := range names {
+				comparison.Counts[name] = 0
+			}
+			comparisons = append(comparisons, comparison)
+		}
+	}
+	return comparisons
+}
+
+func (c Comparison) forbidden(name string) bool {
+	for _, n := range c.Forbidden {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func compareRecords(names []string, results [][]Record, byNamespace bool) []Comparison {
+	var keys []string
+	comparisons := map[string]*Comparison{}
+	for i, records := range results {
+		for _, record := range records {
+			namespace := ""
+			if byNamespace {
+				namespace = record.Namespace
+			}
+			key := strings.Join([]string{namespace, record.GroupVersion, record.Kind, Sample{Breakdown: record.Breakdown}.key()}, "|")
+			comparison, ok := comparisons[key]
+			if !ok {
+				comparison = &Comparison{
+					Namespace:    namespace,
+					GroupVersion: record.GroupVersion,
+					Kind:         record.Kind,
+					Breakdown:    record.Breakdown,
+					Counts:       map[string]int{},
+				}
+				for _, name := range names {
+					comparison.Counts[name] = 0
+				}
+				comparisons[key] = comparison
+				keys = append(keys, key)
+			}
+			comparison.Counts[names[i]] += record.Count
+		}
+	}
+
+	ret := make([]Comparison, 0, len(keys))
+	for _, key := range keys {
+		comparison := comparisons[key]
+		comparison.Delta = comparison.Counts[names[len(names)-1]] - comparison.Counts[names[0]]
+		ret = append(ret, *comparison)
+	}
+	return ret
+}
+
+func (cc *CounterController) comparisonTableRender(w io.Writer, names []string, comparisons []Comparison) {
+	records := make([]Record, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		records = append(records, Record{Breakdown: comparison.Breakdown})
+	}
+	breakdownColumns, _ := cc.columns(records)
+
+	withNamespace := false
+	for _, comparison := range comparisons {
+		if comparison.Namespace != "" {
+			withNamespace = true
+			break
+		}
+	}
+
+	headers := []string{"GroupVersion", "Kind"}
+	if withNamespace {
+		headers = append([]string{"Namespace"}, headers...)
+	}
+	for _, column := range breakdownColumns {
+		headers = append(headers, strings.ToUpper(column[:1])+column[1:])
+	}
+	headers = append(headers, names...)
+	headers = append(headers, "Delta")
+
+	table := cc.newTable(w)
+	table.SetHeader(headers)
+	table.SetAutoFormatHeaders(false)
+	if withNamespace {
+		table.SetAutoMergeCellsByColumnIndex([]int{0, 1, 2})
+	} else {
+		table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
+	}
+	table.SetRowLine(true)
+
+	alignments := make([]int, len(headers))
+	alignments[len(headers)-1] = tablewriter.ALIGN_RIGHT
+	table.SetColumnAlignment(alignments)
+
+	// differences are highlighted when the table goes to a terminal.
+	highlight := cc.outputFile == "" && term.IsTerminal(int(os.Stdout.Fd()))
+	colors := make([]tablewriter.Colors, len(headers))
+	colors[len(headers)-1] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor}
+
+	for _, comparison := range comparisons {
+		row := []string{comparison.GroupVersion, comparison.Kind}
+		if withNamespace {
+			row = append([]string{comparison.Namespace}, row...)
+		}
+		for _, column := range breakdownColumns {
+			row = append(row, comparison.Breakdown[column])
+		}
+		for _, name := range names {
+			if comparison.forbidden(name) {
+				row = append(row, "forbidden")
+				continue
+			}
+			row = append(row, humanizeCount(comparison.Counts[name], cc.humanize))
+		}
+		if len(comparison.Forbidden) > 0 {
+			row = append(row, "")
+		} else {
+			row = append(row, fmt.Sprintf("%+d", comparison.Delta))
+		}
+		if highlight && comparison.Delta != 0 {
+			table.Rich(row, colors)
+			continue
+		}
+		table.Append(row)
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderComparison(names []string, comparisons []Comparison, output string) {
+	if len(comparisons) == 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("ComparisonList", comparisons))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("ComparisonList", comparisons))
+	default:
+		cc.comparisonTableRender(&buf, names, comparisons)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCompareRecords(t *testing.T) {
+	tests := []struct {
+		name        string
+		names       []string
+		results     [][]Record
+		byNamespace bool
+		want        []Comparison
+	}{
+		{
+			name:  "kinds missing on one side",
+			names: []string{"staging", "prod"},
+			results: [][]Record{
+				{
+					{Namespace: "staging", GroupVersion: "v1", Kind: "Pod", Count: 3},
+					{Namespace: "staging", GroupVersion: "apps/v1", Kind: "Deployment", Count: 1},
+				},
+				{
+					{Namespace: "prod", GroupVersion: "v1", Kind: "Pod", Count: 5},
+					{Namespace: "prod", GroupVersion: "v1", Kind: "Secret", Count: 2},
+				},
+			},
+			want: []Comparison{
+				{GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"staging": 3, "prod": 5}, Delta: 2},
+				{GroupVersion: "apps/v1", Kind: "Deployment", Counts: map[string]int{"staging": 1, "prod": 0}, Delta: -1},
+				{GroupVersion: "v1", Kind: "Secret", Counts: map[string]int{"staging": 0, "prod": 2}, Delta: 2},
+			},
+		},
+		{
+			name:  "breakdowns compared apart",
+			names: []string{"staging", "prod"},
+			results: [][]Record{
+				{
+					{GroupVersion: "v1", Kind: "Pod", Breakdown: map[string]string{"phase": "Running"}, Count: 3},
+					{GroupVersion: "v1", Kind: "Pod", Breakdown: map[string]string{"phase": "Pending"}, Count: 1},
+				},
+				{
+					{GroupVersion: "v1", Kind: "Pod", Breakdown: map[string]string{"phase": "Running"}, Count: 4},
+				},
+			},
+			want: []Comparison{
+				{GroupVersion: "v1", Kind: "Pod", Breakdown: map[string]string{"phase": "Running"}, Counts: map[string]int{"staging": 3, "prod": 4}, Delta: 1},
+				{GroupVersion: "v1", Kind: "Pod", Breakdown: map[string]string{"phase": "Pending"}, Counts: map[string]int{"staging": 1, "prod": 0}, Delta: -1},
+			},
+		},
+		{
+			name:  "clusters compared by namespace",
+			names: []string{"old", "new"},
+			results: [][]Record{
+				{
+					{Cluster: "old", Namespace: "default", GroupVersion: "v1", Kind: "Pod", Count: 2},
+					{Cluster: "old", Namespace: "kube-system", GroupVersion: "v1", Kind: "Pod", Count: 1},
+				},
+				{
+					{Cluster: "new", Namespace: "default", GroupVersion: "v1", Kind: "Pod", Count: 2},
+				},
+			},
+			byNamespace: true,
+			want: []Comparison{
+				{Namespace: "default", GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"old": 2, "new": 2}},
+				{Namespace: "kube-system", GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"old": 1, "new": 0}, Delta: -1},
+			},
+		},
+		{
+			name:  "delta between the first and last cluster",
+			names: []string{"a", "b", "c"},
+			results: [][]Record{
+				{{GroupVersion: "v1", Kind: "Pod", Count: 2}},
+				{{GroupVersion: "v1", Kind: "Pod", Count: 9}},
+				{{GroupVersion: "v1", Kind: "Pod", Count: 3}},
+			},
+			byNamespace: true,
+			want: []Comparison{
+				{GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"a": 2, "b": 9, "c": 3}, Delta: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareRecords(tt.names, tt.results, tt.byNamespace)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareRecords() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkForbidden(t *testing.T) {
+	names := []string{"current", "jane"}
+	comparisons := []Comparison{
+		{Namespace: "default", GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"current": 3, "jane": 0}, Delta: -3},
+		{Namespace: "kube-system", GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"current": 1, "jane": 0}, Delta: -1},
+		{Namespace: "default", GroupVersion: "v1", Kind: "ConfigMap", Counts: map[string]int{"current": 2, "jane": 2}},
+	}
+	forbidden := [][]Record{
+		{{GroupVersion: "v1", Kind: "Secret"}},
+		{{GroupVersion: "v1", Kind: "Pod"}, {GroupVersion: "v1", Kind: "Secret"}},
+	}
+
+	want := []Comparison{
+		{Namespace: "default", GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"current": 3, "jane": 0}, Delta: -3, Forbidden: []string{"jane"}},
+		{Namespace: "kube-system", GroupVersion: "v1", Kind: "Pod", Counts: map[string]int{"current": 1, "jane": 0}, Delta: -1, Forbidden: []string{"jane"}},
+		{Namespace: "default", GroupVersion: "v1", Kind: "ConfigMap", Counts: map[string]int{"current": 2, "jane": 2}},
+		{GroupVersion: "v1", Kind: "Secret", Counts: map[string]int{"current": 0, "jane": 0}, Forbidden: []string{"current", "jane"}},
+	}
+	if got := markForbidden(names, forbidden, comparisons); !reflect.DeepEqual(got, want) {
+		t.Errorf("markForbidden() = %+v, want %+v", got, want)
+	}
+	if !want[3].forbidden("jane") || want[2].forbidden("jane") {
+		t.Errorf("forbidden() doesn't match the identities marked")
+	}
+}
+
+func TestVisibleRecords(t *testing.T) {
+	resource := func(name, kind string, namespaced bool) v1.APIResource {
+		return v1.APIResource{Name: name, Kind: kind, Namespaced: namespaced, Verbs: v1.Verbs{"list", "watch"}}
+	}
+	discovery := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: []*v1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []v1.APIResource{
+			resource("pods", "Pod", true),
+			resource("secrets", "Secret", true),
+			resource("configmaps", "ConfigMap", true),
+			resource("namespaces", "Namespace", false),
+		},
+	}}}}
+	object := func(kind, namespace, name string) runtime.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		return obj
+	}
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Version: "v1", Resource: "pods"}:       "PodList",
+			{Version: "v1", Resource: "secrets"}:    "SecretList",
+			{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+			{Version: "v1", Resource: "namespaces"}: "NamespaceList",
+		},
+		object("Pod", "team-a", "a"), object("Pod", "team-b", "b"),
+		object("Secret", "team-a", "a"), object("Secret", "team-a", "b"), object("Secret", "team-b", "c"),
+		object("ConfigMap", "team-b", "a"),
+	)
+	// pods are listed cluster-wide, secrets in team-a only and configmaps nowhere.
+	dynamicClient.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		resource := action.GetResource().Resource
+		switch {
+		case resource == "pods", resource == "secrets" && action.GetNamespace() == "team-a":
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(action.GetResource().GroupResource(), "", nil)
+	})
+
+	cc := &CounterController{
+		ctx:             context.Background(),
+		discoveryClient: memory.NewMemCacheClient(discovery),
+		dynamicClient:   dynamicClient,
+		timing:          NewTiming(),
+		retryStats:      NewRetryStats(),
+	}
+	records, forbidden := cc.visibleRecords("jane", "pods,secrets,configmaps", false, func() []string {
+		return []string{"team-a", "team-b"}
+	})
+
+	wantRecords := []Record{
+		{Namespace: "team-a", GroupVersion: "v1", Kind: "Pod", Count: 1},
+		{Namespace: "team-b", GroupVersion: "v1", Kind: "Pod", Count: 1},
+		{Namespace: "team-a", GroupVersion: "v1", Kind: "Secret", Count: 2},
+	}
+	var got []Record
+	for _, record := range records {
+		got = append(got, Record{Namespace: record.Namespace, GroupVersion: record.GroupVersion, Kind: record.Kind, Count: record.Count})
+	}
+	if !reflect.DeepEqual(got, wantRecords) {
+		t.Errorf("visibleRecords() = %+v, want %+v", got, wantRecords)
+	}
+	wantForbidden := []Record{{GroupVersion: "v1", Kind: "ConfigMap"}}
+	if !reflect.DeepEqual(forbidden, wantForbidden) {
+		t.Errorf("visibleRecords() forbidden = %+v, want %+v", forbidden, wantForbidden)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// kubeconfigValue accepts --kubeconfig repeated or as a list separated like KUBECONFIG.
+type kubeconfigValue struct {
+	p *string
+}
+
+func (kv *kubeconfigValue) String() string { return *kv.p }
+func (kv *kubeconfigValue) Type() string   { return "string" }
+
+func (kv *kubeconfigValue) Set(s string) error {
+	if *kv.p != "" {
+		s = *kv.p + string(filepath.ListSeparator) + s
+	}
+	*kv.p = s
+	return nil
+}
+
+// mergeKubeconfigs hands several kubeconfig files over to KUBECONFIG, the loading rules merge its files
+// with the same precedence as kubectl while --kubeconfig only loads a single file.
+func mergeKubeconfigs() error {
+	files := filepath.SplitList(*cf.KubeConfig)
+	if len(files) < 2 {
+		return nil
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+	}
+	if err := os.Setenv("KUBECONFIG", strings.Join(files, string(filepath.ListSeparator))); err != nil {
+		return err
+	}
+	*cf.KubeConfig = ""
+	return nil
+}
+
+func contextNames() ([]string, error) {
+	rawConfig, err := cf.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(rawConfig.Contexts))
+	for name := range rawConfig.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func clusterConfigFlags(kubeconfig, context string) *genericclioptions.ConfigFlags {
+	flags := genericclioptions.NewConfigFlags(true)
+	flags.KubeConfig = cf.KubeConfig
+	flags.CacheDir = cf.CacheDir
+	flags.Impersonate = cf.Impersonate
+	flags.ImpersonateUID = cf.ImpersonateUID
+	flags.ImpersonateGroup = cf.ImpersonateGroup
+	flags.Insecure = cf.Insecure
+	flags.Timeout = cf.Timeout
+	if kubeconfig != "" {
+		flags.KubeConfig = &kubeconfig
+	}
+	if context != "" {
+		flags.Context = &context
+	}
+	return flags
+}
+
+func RenderContexts(cmd *cobra.Command, kinds, order, output string, allNamespace bool) {
+	names, err := contextNames()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to load kubeconfig contexts, error: %v", err)
+		exit(exitFailure)
+	}
+
+	clusters := make([]Cluster, 0, len(names))
+	for _, name := range names {
+		clusters = append(clusters, Cluster{Name: name, Context: name})
+	}
+	RenderFleet(cmd, clusters, kinds, order, output, allNamespace)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type CoverageRecord struct {
+	Namespace    string `json:"namespace" yaml:"namespace"`
+	GroupVersion string `json:"groupVersion" yaml:"groupVersion"`
+	Kind         string `json:"kind" yaml:"kind"`
+	Covered      int    `json:"covered" yaml:"covered"`
+	Uncovered    int    `json:"uncovered" yaml:"uncovered"`
+}
+
+func (cr CoverageRecord) percent() string {
+	total := cr.Covered + cr.Uncovered
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(cr.Covered)*100/float64(total))
+}
+
+var coverageCmd = &cobra.Command{
+	Use:   "coverage <hpa|netpol>",
+	Short: "Count the workloads covered by a policy object and the ones which aren't.",
+	Example: `  # count the deployments and statefulsets with and without an HPA in every namespace.
+  kubectl count coverage hpa
+
+  # count the namespaces and pods selected by at least one NetworkPolicy.
+  kubectl count coverage netpol -A`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"hpa", "netpol"},
+	Run: func(cmd *cobra.Command, args []string) {
+		order, _ := cmd.Flags().GetString("order")
+		format, _ := cmd.Flags().GetString("output-format")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+		namespace, _ := cmd.Flags().GetString("namespace")
+
+		ctr := mustController(cmd)
+		var records []CoverageRecord
+		var err error
+		switch args[0] {
+		case "hpa", "horizontalpodautoscalers":
+			records, err = ctr.hpaCoverage(namespace)
+		case "netpol", "networkpolicies":
+			records, err = ctr.networkPolicyCoverage(namespace)
+		default:
+			fmt.Fprintf(os.Stderr, "[Oh...] Unknown coverage '%s', use hpa or netpol!\n", args[0])
+			exit(exitFailure)
+		}
+		if err != nil {
+			logger.Error("list resources failed", err)
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+			exit(exitCode(err))
+		}
+		ctr.RenderCoverage(records, order, format, allNamespace)
+		exit(0)
+	},
+}
+
+func (cc *CounterController) listResource(name, namespace string) (*APIResourceGV, []unstructured.Unstructured, error) {
+	apiResources, err := cc.getApiResources()
+	if err != nil {
+		return nil, nil, err
+	}
+	ars, ok := apiResources[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("resource %s not served by the cluster", name)
+	}
+
+	agv := ars[0]
+	gvr := schema.GroupVersionResource{Group: agv.resource.Group, Version: agv.resource.Version, Resource: agv.resource.Name}
+	list, err := cc.dynamicClient.Resource(gvr).Namespace(namespace).List(cc.ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &agv, list.Items, nil
+}
+
+func (cc *CounterController) hpaCoverage(namespace string) ([]CoverageRecord, error) {
+	_, hpas, err := cc.listResource("horizontalpodautoscalers.autoscaling", namespace)
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string]bool{}
+	for _, hpa := range hpas {
+		kind, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "kind")
+		name, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "name")
+		targets[strings.Join([]string{hpa.GetNamespace(), kind, name}, "/")] = true
+	}
+
+	var records []CoverageRecord
+	for _, resource := range []string{"deployments.apps", "statefulsets.apps"} {
+		agv, workloads, err := cc.listResource(resource, namespace)
+		if err != nil {
+			return nil, err
+		}
+		covered := map[string]*CoverageRecord{}
+		for _, workload := range workloads {
+			r, ok := covered[workload.GetNamespace()]
+			if !ok {
+				r = &CoverageRecord{Namespace: workload.GetNamespace(), GroupVersion: agv.groupVersion, Kind: agv.resource.Kind}
+				covered[workload.GetNamespace()] = r
+			}
+			if targets[strings.Join([]string{workload.GetNamespace(), workload.GetKind(), workload.GetName()}, "/")] {
+				r.Covered++
+			} else {
+				r.Uncovered++
+			}
+		}
+		for _, r := range covered {
+			records = append(records, *r)
+		}
+	}
+	return records, nil
+}
+
+func (cc *CounterController) networkPolicyCoverage(namespace string) ([]CoverageRecord, error) {
+	_, policies, err := cc.listResource("networkpolicies.networking.k8s.io", namespace)
+	if err != nil {
+		return nil, err
+	}
+	selectors := map[string][]labels.Selector{}
+	for _, policy := range policies {
+		podSelector, _, _ := unstructured.NestedMap(policy.Object, "spec", "podSelector")
+		ls := &v1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podSelector, ls); err != nil {
+			return nil, err
+		}
+		selector, err := v1.LabelSelectorAsSelector(ls)
+		if err != nil {
+			return nil, err
+		}
+		selectors[policy.GetNamespace()] = append(selectors[policy.GetNamespace()], selector)
+	}
+
+	nsResource, namespaces, err := cc.listResource("namespaces", "")
+	if err != nil {
+		return nil, err
+	}
+	var records []CoverageRecord
+	for _, ns := range namespaces {
+		if namespace != "" && ns.GetName() != namespace {
+			continue
+		}
+		r := CoverageRecord{Namespace: ns.GetName(), GroupVersion: nsResource.groupVersion, Kind: nsResource.resource.Kind}
+		if len(selectors[ns.GetName()]) > 0 {
+			r.Covered++
+		} else {
+			r.Uncovered++
+		}
+		records = append(records, r)
+	}
+
+	podResource, pods, err := cc.listResource("pods", namespace)
+	if err != nil {
+		return nil, err
+	}
+	covered := map[string]*CoverageRecord{}
+	for _, pod := range pods {
+		r, ok := covered[pod.GetNamespace()]
+		if !ok {
+			r = &CoverageRecord{Namespace: pod.GetNamespace(), GroupVersion: podResource.groupVersion, Kind: podResource.resource.Kind}
+			covered[pod.GetNamespace()] = r
+		}
+		selected := false
+		for _, selector := range selectors[pod.GetNamespace()] {
+			if selector.Matches(labels.Set(pod.GetLabels())) {
+				selected = true
+				break
+			}
+		}
+		if selected {
+			r.Covered++
+		} else {
+			r.Uncovered++
+		}
+	}
+	for _, r := range covered {
+		records = append(records, *r)
+	}
+	return records, nil
+}
+
+func coverageRecords(records []CoverageRecord, order string, allNamespace bool) []CoverageRecord {
+	if allNamespace {
+		var keys []string
+		merged := map[string]*CoverageRecord{}
+		for _, record := range records {
+			key := record.GroupVersion + "/" + record.Kind
+			r, ok := merged[key]
+			if !ok {
+				r = &CoverageRecord{GroupVersion: record.GroupVersion, Kind: record.Kind}
+				merged[key] = r
+				keys = append(keys, key)
+			}
+			r.Covered += record.Covered
+			r.Uncovered += record.Uncovered
+		}
+		records = make([]CoverageRecord, 0, len(keys))
+		for _, key := range keys {
+			records = append(records, *merged[key])
+		}
+	}
+
+	// the least covered namespaces come first in descending order.
+	desc := isDesc(order)
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Uncovered != records[j].Uncovered {
+			if desc {
+				return records[i].Uncovered > records[j].Uncovered
+			}
+			return records[i].Uncovered < records[j].Uncovered
+		}
+		if records[i].Namespace != records[j].Namespace {
+			return records[i].Namespace < records[j].Namespace
+		}
+		return records[i].Kind < records[j].Kind
+	})
+	return records
+}
+
+func (cc *CounterController) coverageTableRender(w io.Writer, records []CoverageRecord) {
+	headers := []string{"Namespace", "GroupVersion", "Kind", "Covered", "Uncovered", "Coverage"}
+	table := cc.newTable(w)
+	table.SetHeader(headers)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1, 2})
+	table.SetRowLine(true)
+
+	alignments := make([]int, len(headers))
+	alignments[len(headers)-1] = tablewriter.ALIGN_RIGHT
+	table.SetColumnAlignment(alignments)
+
+	for _, record := range records {
+		table.Append([]string{
+			record.Namespace,
+			record.GroupVersion,
+			record.Kind,
+			humanizeCount(record.Covered, cc.humanize),
+			humanizeCount(record.Uncovered, cc.humanize),
+			record.percent(),
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderCoverage(records []CoverageRecord, order, output string, allNamespace bool) {
+	records = coverageRecords(records, order, allNamespace)
+	if len(records) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+		records = []CoverageRecord{}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("CoverageList", records))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("CoverageList", records))
+	default:
+		cc.coverageTableRender(&buf, records)
+	}
+	cc.write(buf.Bytes())
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type CRDVersion struct {
+	CRD     string `json:"crd" yaml:"crd"`
+	Version string `json:"version" yaml:"version"`
+	Served  bool   `json:"served" yaml:"served"`
+	Storage bool   `json:"storage" yaml:"storage"`
+	Stored  bool   `json:"stored" yaml:"stored"`
+	Count   int    `json:"count" yaml:"count"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+var crdVersionsCmd = &cobra.Command{
+	Use:   "crd-versions [crd...]",
+	Short: "Count the instances of CustomResourceDefinitions per served version.",
+	Example: `  # count the instances of every CRD per served version.
+  kubectl count crd-versions
+
+  # check which versions of a CRD still have objects stored before dropping them.
+  kubectl count crd-versions certificates.cert-manager.io`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output-format")
+		namespace, _ := cmd.Flags().GetString("namespace")
+
+		ctr := mustController(cmd)
+		versions, err := ctr.crdVersions(args, namespace)
+		if err != nil {
+			logger.Error("list resources failed", err)
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+			exit(exitCode(err))
+		}
+		ctr.RenderCRDVersions(versions, format)
+		exit(0)
+	},
+}
+
+func (cc *CounterController) crdVersions(names []string, namespace string) ([]CRDVersion, error) {
+	_, crds, err := cc.listResource("customresourcedefinitions.apiextensions.k8s.io", "")
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = true
+	}
+	sort.Slice(crds, func(i, j int) bool {
+		return crds[i].GetName() < crds[j].GetName()
+	})
+
+	var ret []CRDVersion
+	for _, crd := range crds {
+		if len(wanted) > 0 && !wanted[crd.GetName()] {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+		scope, _, _ := unstructured.NestedString(crd.Object, "spec", "scope")
+		storedVersions, _, _ := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+		stored := map[string]bool{}
+		for _, version := range storedVersions {
+			stored[version] = true
+		}
+
+		items, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		for _, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			version, _, _ := unstructured.NestedString(m, "name")
+			served, _, _ := unstructured.NestedBool(m, "served")
+			storage, _, _ := unstructured.NestedBool(m, "storage")
+			cv := CRDVersion{
+				CRD:     crd.GetName(),
+				Version: version,
+				Served:  served,
+				Storage: storage,
+				Stored:  stored[version],
+			}
+
+			// objects are converted to the requested version on reads, so a
+			// failing conversion webhook shows up here rather than aborting.
+			if served {
+				ri := cc.dynamicClient.Resource(schema.GroupVersionResource{Group: group, Version: version, Resource: plural})
+				var list *unstructured.UnstructuredList
+				if scope == "Namespaced" {
+					list, err = ri.Namespace(namespace).List(cc.ctx, v1.ListOptions{})
+				} else {
+					list, err = ri.List(cc.ctx, v1.ListOptions{})
+				}
+				if err != nil {
+					cv.Error = err.Error()
+				} else {
+					cv.Count = len(list.Items)
+				}
+			}
+			ret = append(ret, cv)
+		}
+	}
+	return ret, nil
+}
+
+func (cc *CounterController) crdVersionTableRender(w io.Writer, versions []CRDVersion) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"CRD", "Version", "Served", "Storage", "Stored", "Count"})
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetRowLine(true)
+
+	mark := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "-"
+	}
+	for _, cv := range versions {
+		count := humanizeCount(cv.Count, cc.humanize)
+		switch {
+		case cv.Error != "":
+			count = "error"
+		case !cv.Served:
+			count = "-"
+		}
+		table.Append([]string{cv.CRD, cv.Version, mark(cv.Served), mark(cv.Storage), mark(cv.Stored), count})
+	}
+	table.Render()
+
+	for _, cv := range versions {
+		if cv.Error != "" {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to list %s in version %s, error: %s\n", cv.CRD, cv.Version, cv.Error)
+		}
+	}
+}
+
+func (cc *CounterController) RenderCRDVersions(versions []CRDVersion, output string) {
+	if len(versions) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+		versions = []CRDVersion{}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("CRDVersionList", versions))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("CRDVersionList", versions))
+	default:
+		cc.crdVersionTableRender(&buf, versions)
+	}
+	cc.write(buf.Bytes())
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/disk"
+	"k8s.io/client-go/util/homedir"
+	"k8s.io/klog/v2"
+)
+
+const defaultCacheTTL = 6 * time.Hour
+
+var illegalFileCharacters = regexp.MustCompile(`[^(\w/.)]`)
+
+func discoveryCacheDir(parentDir, host string) string {
+	schemelessHost := strings.Replace(strings.Replace(host, "https://", "", 1), "http://", "", 1)
+	return filepath.Join(parentDir, illegalFileCharacters.ReplaceAllString(schemelessHost, "_"))
+}
+
+func newDiscoveryClient(configFlags *genericclioptions.ConfigFlags, ttl time.Duration, noCache bool) (discovery.CachedDiscoveryInterface, error) {
+	config, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.Burst = 100
+
+	cacheDir := filepath.Join(homedir.HomeDir(), ".kube", "cache")
+	if configFlags.CacheDir != nil && *configFlags.CacheDir != "" {
+		cacheDir = *configFlags.CacheDir
+	}
+
+	dc, err := disk.NewCachedDiscoveryClientForConfig(
+		config,
+		discoveryCacheDir(filepath.Join(cacheDir, "discovery"), config.Host),
+		filepath.Join(cacheDir, "http"),
+		ttl,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if noCache {
+		dc.Invalidate()
+	}
+	return dc, nil
+}
+
+func (cc *CounterController) discoveryFailed(err error, resources int) error {
+	if err == nil {
+		return nil
+	}
+	klog.V(1).Infof("discovery returned partial results: %v", err)
+	logger.Error("discovery returned partial results", err)
+
+	// an aggregated API being down fails its own group only, the kinds of
+	// the other groups can still be counted.
+	if resources == 0 || cc.strictDiscovery {
+		return err
+	}
+	var failed *discovery.ErrGroupDiscoveryFailed
+	if !errors.As(err, &failed) {
+		return nil
+	}
+	cc.discoveryWarned.Do(func() {
+		groupVersions := make([]string, 0, len(failed.Groups))
+		for gv, err := range failed.Groups {
+			groupVersions = append(groupVersions, fmt.Sprintf("%s (%v)", gv.String(), err))
+		}
+		sort.Strings(groupVersions)
+		fmt.Fprintf(os.Stderr, "[Warning] Failed to discover %s, their kinds are not counted, use --strict-discovery to fail instead\n", strings.Join(groupVersions, ", "))
+		cc.warn("DiscoveryFailed", "failed to discover %s, their kinds are not counted", strings.Join(groupVersions, ", "))
+	})
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+type DuplicateName struct {
+	GroupVersion string   `json:"groupVersion" yaml:"groupVersion"`
+	Kind         string   `json:"kind" yaml:"kind"`
+	Name         string   `json:"name" yaml:"name"`
+	Count        int      `json:"count" yaml:"count"`
+	Namespaces   []string `json:"namespaces" yaml:"namespaces"`
+}
+
+type namedObject struct {
+	GroupVersion string
+	Kind         string
+	Name         string
+}
+
+type namespacedObject struct {
+	namedObject
+	Namespace string
+}
+
+type NameMap struct {
+	lock sync.Mutex
+	m    map[types.UID]namespacedObject
+}
+
+func NewNameMap() *NameMap {
+	return &NameMap{
+		m: map[types.UID]namespacedObject{},
+	}
+}
+
+func (nm *NameMap) observe(obj interface{}) {
+	o, ok := obj.(*unstructured.Unstructured)
+	// names of cluster scoped objects are unique already.
+	if !ok || o.GetNamespace() == "" {
+		return
+	}
+
+	nm.lock.Lock()
+	defer nm.lock.Unlock()
+	nm.m[o.GetUID()] = namespacedObject{
+		namedObject: namedObject{GroupVersion: o.GetAPIVersion(), Kind: o.GetKind(), Name: o.GetName()},
+		Namespace:   o.GetNamespace(),
+	}
+}
+
+func (nm *NameMap) Handler(id string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: nm.observe,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			nm.observe(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			o, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			nm.lock.Lock()
+			defer nm.lock.Unlock()
+			delete(nm.m, o.GetUID())
+		},
+	}
+}
+
+func (nm *NameMap) GetDuplicates(order string) []DuplicateName {
+	nm.lock.Lock()
+	defer nm.lock.Unlock()
+
+	namespaces := map[namedObject][]string{}
+	for _, o := range nm.m {
+		namespaces[o.namedObject] = append(namespaces[o.namedObject], o.Namespace)
+	}
+
+	ret := make([]DuplicateName, 0)
+	for named, ns := range namespaces {
+		if len(ns) < 2 {
+			continue
+		}
+		sort.Strings(ns)
+		ret = append(ret, DuplicateName{
+			GroupVersion: named.GroupVersion,
+			Kind:         named.Kind,
+			Name:         named.Name,
+			Count:        len(ns),
+			Namespaces:   ns,
+		})
+	}
+
+	desc := isDesc(order)
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			if desc {
+				return ret[i].Count > ret[j].Count
+			}
+			return ret[i].Count < ret[j].Count
+		}
+		if ret[i].GroupVersion != ret[j].GroupVersion {
+			return ret[i].GroupVersion < ret[j].GroupVersion
+		}
+		if ret[i].Kind != ret[j].Kind {
+			return ret[i].Kind < ret[j].Kind
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func (cc *CounterController) names(s string) (*NameMap, error) {
+	defer cc.cancel()
+
+	nameMap := NewNameMap()
+	if _, err := cc.sync(s, nameMap.Handler); err != nil && !errors.Is(err, errInterrupted) {
+		return nil, err
+	}
+	return nameMap, nil
+}
+
+func (cc *CounterController) duplicateTableRender(w io.Writer, duplicates []DuplicateName) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"GroupVersion", "Kind", "Name", "Namespaces"})
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
+	table.SetRowLine(true)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT})
+
+	for _, duplicate := range duplicates {
+		table.Append([]string{
+			duplicate.GroupVersion,
+			duplicate.Kind,
+			duplicate.Name,
+			humanizeCount(duplicate.Count, cc.humanize),
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderDuplicates(kinds, order, output string) {
+	nameMap, err := cc.names(kinds)
+	cc.summarize()
+	if err != nil {
+		logger.Error("list resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	duplicates := nameMap.GetDuplicates(order)
+	if len(duplicates) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("DuplicateNameList", duplicates))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("DuplicateNameList", duplicates))
+	default:
+		cc.duplicateTableRender(&buf, duplicates)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type OutputError struct {
+	Reason  string `json:"reason" yaml:"reason"`
+	Cluster string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+type OutputWarning struct {
+	Reason  string `json:"reason" yaml:"reason"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (cc *CounterController) warn(reason, format string, args ...interface{}) {
+	cc.warningsLock.Lock()
+	defer cc.warningsLock.Unlock()
+	cc.warnings = append(cc.warnings, OutputWarning{Reason: reason, Message: fmt.Sprintf(format, args...)})
+}
+
+// --strict fails a run which only warned otherwise.
+func (cc *CounterController) escalateWarnings(output string) {
+	cc.warningsLock.Lock()
+	warnings := cc.warnings
+	cc.warningsLock.Unlock()
+	if !cc.strict || len(warnings) == 0 {
+		return
+	}
+
+	messages := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		messages = append(messages, warning.Message)
+	}
+	cc.fail(output, "Failed with --strict", fmt.Errorf("warnings escalated by --strict: %s", strings.Join(messages, "; ")))
+}
+
+func newOutputError(cluster string, err error) OutputError {
+	reason := "Failed"
+	switch exitCode(err) {
+	case exitInterrupted:
+		reason = "Interrupted"
+	case exitAccessDenied:
+		reason = "AccessDenied"
+	}
+	return OutputError{Reason: reason, Cluster: cluster, Message: err.Error()}
+}
+
+func clusterErrors(errs map[string]error) []OutputError {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make([]OutputError, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, newOutputError(name, errs[name]))
+	}
+	return ret
+}
+
+// json and yaml carry the errors in the envelope, automation parses a single
+// document rather than stderr.
+func structuredOutput(output string) bool {
+	switch output {
+	case "json", "j", "yaml", "y":
+		return true
+	}
+	return false
+}
+
+func renderErrors(w io.Writer, output string, errs []OutputError) {
+	envelope := newEnvelope("RecordList", []Record{})
+	envelope.Errors = errs
+
+	var b []byte
+	var err error
+	switch output {
+	case "json", "j":
+		b, err = json.MarshalIndent(envelope, "", " ")
+	default:
+		b, err = yaml.Marshal(envelope)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to marshal output, error: %v", err)
+		exit(exitFailure)
+	}
+	fmt.Fprintln(w, string(b))
+}
+
+func (cc *CounterController) fail(output, message string, err error) {
+	if structuredOutput(output) {
+		var buf bytes.Buffer
+		renderErrors(&buf, output, []OutputError{newOutputError("", err)})
+		cc.write(buf.Bytes())
+		exit(exitCode(err))
+	}
+	fmt.Fprintf(os.Stderr, "[Oh...] %s, error: %v", message, err)
+	exit(exitCode(err))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type Candidate struct {
+	ResolvedResource `yaml:",inline"`
+	MatchedBy        []string `json:"matchedBy" yaml:"matchedBy"`
+	Categories       []string `json:"categories" yaml:"categories"`
+	Counted          bool     `json:"counted" yaml:"counted"`
+	Preferred        bool     `json:"preferred" yaml:"preferred"`
+}
+
+type Explanation struct {
+	Input      string      `json:"input" yaml:"input"`
+	Candidates []Candidate `json:"candidates" yaml:"candidates"`
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <kinds>",
+	Short: "Explain which resources the kinds resolve to and why.",
+	Example: `  # show every resource cj could mean, how it matched and which one kubectl prefers.
+  kubectl count explain cj`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output-format")
+		mustController(cmd).RenderExplain(mustKinds(cmd, args), format)
+		exit(0)
+	},
+}
+
+func (cc *CounterController) explain(s string) ([]Explanation, error) {
+	kinds := cc.sanitizeKinds(s)
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("invalid input kind name: '%s'", s)
+	}
+
+	agvs, err := cc.serverResources()
+	if err != nil {
+		return nil, err
+	}
+
+	explanations := make([]Explanation, 0, len(kinds))
+	for _, kind := range kinds {
+		explanation := Explanation{Input: kind, Candidates: []Candidate{}}
+		preferred := false
+		// candidates keep the discovery order, the first one counted is the
+		// one kubectl picks when the kind is ambiguous.
+		for _, agv := range agvs {
+			matchedBy := agv.keys()[kind]
+			for _, category := range agv.resource.Categories {
+				if category == kind {
+					matchedBy = append(matchedBy, "category")
+				}
+			}
+			if len(matchedBy) == 0 {
+				continue
+			}
+
+			categories := agv.resource.Categories
+			if categories == nil {
+				categories = []string{}
+			}
+			candidate := Candidate{
+				ResolvedResource: ResolvedResource{
+					Group:      agv.resource.Group,
+					Version:    agv.resource.Version,
+					Resource:   agv.resource.Name,
+					Kind:       agv.resource.Kind,
+					Namespaced: agv.resource.Namespaced,
+				},
+				MatchedBy:  matchedBy,
+				Categories: categories,
+				Counted:    len(agv.keys()[kind]) > 0 && agv.listable(),
+			}
+			if candidate.Counted && !preferred {
+				candidate.Preferred = true
+				preferred = true
+			}
+			explanation.Candidates = append(explanation.Candidates, candidate)
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations, nil
+}
+
+func (cc *CounterController) explainTableRender(w io.Writer, explanations []Explanation) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"Input", "Group", "Version", "Resource", "Kind", "MatchedBy", "Categories", "Counted", "Preferred"})
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetRowLine(true)
+
+	for _, explanation := range explanations {
+		if len(explanation.Candidates) == 0 {
+			table.Append([]string{explanation.Input, "", "", "", "", "", "", "", ""})
+			continue
+		}
+		for _, c := range explanation.Candidates {
+			table.Append([]string{
+				explanation.Input,
+				c.Group,
+				c.Version,
+				c.Resource,
+				c.Kind,
+				strings.Join(c.MatchedBy, ","),
+				strings.Join(c.Categories, ","),
+				strconv.FormatBool(c.Counted),
+				strconv.FormatBool(c.Preferred),
+			})
+		}
+	}
+	table.Render()
+
+	for _, explanation := range explanations {
+		counted, unlistable := 0, 0
+		for _, c := range explanation.Candidates {
+			if c.Counted {
+				counted++
+				continue
+			}
+			for _, matchedBy := range c.MatchedBy {
+				if matchedBy != "category" {
+					unlistable++
+					break
+				}
+			}
+		}
+		switch {
+		case len(explanation.Candidates) == 0:
+			fmt.Fprintf(w, "%s doesn't match any resource served by the cluster.\n", explanation.Input)
+		case counted == 0 && unlistable > 0:
+			fmt.Fprintf(w, "%s matches resources which can't be listed and watched, they aren't counted.\n", explanation.Input)
+		case counted == 0:
+			fmt.Fprintf(w, "%s only matches a category, categories aren't expanded when counting.\n", explanation.Input)
+		case counted > 1:
+			fmt.Fprintf(w, "%s is ambiguous, kubectl count counts all %d resources while kubectl only uses the preferred one.\n", explanation.Input, counted)
+		}
+	}
+}
+
+func (cc *CounterController) RenderExplain(kinds, output string) {
+	explanations, err := cc.explain(kinds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to explain kinds, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("ExplanationList", explanations))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("ExplanationList", explanations))
+	default:
+		cc.explainTableRender(&buf, explanations)
+	}
+	cc.write(buf.Bytes())
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const externalMetricsGroupVersion = "external.metrics.k8s.io/v1beta1"
+
+type ExternalMetricValue struct {
+	MetricName   string            `json:"metricName"`
+	MetricLabels map[string]string `json:"metricLabels"`
+	Timestamp    v1.Time           `json:"timestamp"`
+	Value        resource.Quantity `json:"value"`
+}
+
+type ExternalMetricValueList struct {
+	v1.TypeMeta `json:",inline"`
+	v1.ListMeta `json:"metadata"`
+	Items       []ExternalMetricValue `json:"items"`
+}
+
+func recordLabels(record Record) labels.Set {
+	set := labels.Set{
+		"namespace":     record.Namespace,
+		"group_version": record.GroupVersion,
+		"kind":          record.Kind,
+	}
+	if record.Cluster != "" {
+		set["cluster"] = record.Cluster
+	}
+	for key, value := range record.Breakdown {
+		set[promLabelInvalid.ReplaceAllString(key, "_")] = value
+	}
+	return set
+}
+
+func (cc *CounterController) externalMetricsRoutes(mux *http.ServeMux, idMap *IDMap, allNamespace bool) {
+	prefix := "/apis/" + externalMetricsGroupVersion
+
+	mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		cc.jsonRender(w, v1.APIResourceList{
+			TypeMeta:     v1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: externalMetricsGroupVersion,
+			APIResources: []v1.APIResource{{Name: metricName, Namespaced: true, Kind: "ExternalMetricValueList", Verbs: []string{"get"}}},
+		})
+	})
+
+	// GET /apis/external.metrics.k8s.io/v1beta1/namespaces/<namespace>/<metric>?labelSelector=<selector>
+	mux.HandleFunc(prefix+"/namespaces/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix+"/namespaces/"), "/")
+		if len(parts) != 2 || parts[1] != metricName {
+			http.Error(w, "metric not found, only "+metricName+" is served", http.StatusNotFound)
+			return
+		}
+		selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// cluster scoped kinds and -A totals have no namespace and are visible
+		// from every namespace.
+		namespace := parts[0]
+		now := v1.Now()
+		items := make([]ExternalMetricValue, 0)
+		for _, record := range idMap.GetRecords("asc", allNamespace) {
+			if record.Namespace != namespace && record.Namespace != "" {
+				continue
+			}
+			set := recordLabels(record)
+			if !selector.Matches(set) {
+				continue
+			}
+			items = append(items, ExternalMetricValue{
+				MetricName:   metricName,
+				MetricLabels: set,
+				Timestamp:    now,
+				Value:        *resource.NewQuantity(int64(record.Count), resource.DecimalSI),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		cc.jsonRender(w, ExternalMetricValueList{
+			TypeMeta: v1.TypeMeta{Kind: "ExternalMetricValueList", APIVersion: externalMetricsGroupVersion},
+			Items:    items,
+		})
+	})
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "kubectl-count"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost", "kubectl-count"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const maxOwnerDepth = 8
+
+type Filter interface {
+	Match(obj *unstructured.Unstructured) bool
+}
+
+type NameFilter struct {
+	Pattern *regexp.Regexp
+}
+
+func NewNameFilter(expr string) (NameFilter, error) {
+	// the whole name has to match, as if the expression was anchored.
+	pattern, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return NameFilter{}, err
+	}
+	return NameFilter{Pattern: pattern}, nil
+}
+
+func (nf NameFilter) Match(obj *unstructured.Unstructured) bool {
+	return nf.Pattern.MatchString(obj.GetName())
+}
+
+type MissingLabelFilter struct {
+	Key string
+}
+
+func (mf MissingLabelFilter) Match(obj *unstructured.Unstructured) bool {
+	_, ok := obj.GetLabels()[mf.Key]
+	return !ok
+}
+
+type NamespaceFilter struct {
+	Namespaces map[string]bool
+}
+
+func (nf NamespaceFilter) Match(obj *unstructured.Unstructured) bool {
+	// the selected namespaces themselves are the only cluster scoped objects kept.
+	if obj.GetNamespace() == "" {
+		return obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace" && nf.Namespaces[obj.GetName()]
+	}
+	return nf.Namespaces[obj.GetNamespace()]
+}
+
+func (cc *CounterController) NewNamespaceFilter(selector string) (NamespaceFilter, error) {
+	if _, err := labels.Parse(selector); err != nil {
+		return NamespaceFilter{}, err
+	}
+	list, err := cc.dynamicClient.Resource(namespacesGVR).List(cc.ctx, v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return NamespaceFilter{}, err
+	}
+	namespaces := map[string]bool{}
+	for _, item := range list.Items {
+		namespaces[item.GetName()] = true
+	}
+	return NamespaceFilter{Namespaces: namespaces}, nil
+}
+
+type OwnerFilter struct {
+	uid   types.UID
+	get   func(ref v1.OwnerReference, namespace string) (*unstructured.Unstructured, error)
+	lock  sync.Mutex
+	owned map[types.UID]bool
+}
+
+func (of *OwnerFilter) Match(obj *unstructured.Unstructured) bool {
+	return of.owns(obj, 0)
+}
+
+func (of *OwnerFilter) owns(obj *unstructured.Unstructured, depth int) bool {
+	if depth > maxOwnerDepth {
+		return false
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == of.uid || of.ownedRef(ref, obj.GetNamespace(), depth) {
+			return true
+		}
+	}
+	return false
+}
+
+func (of *OwnerFilter) ownedRef(ref v1.OwnerReference, namespace string, depth int) bool {
+	of.lock.Lock()
+	owned, ok := of.owned[ref.UID]
+	of.lock.Unlock()
+	if ok {
+		return owned
+	}
+
+	// owners which are gone or can't be read are remembered as not owned, the
+	// chain is walked up at most once per owner.
+	owner, err := of.get(ref, namespace)
+	if err != nil {
+		logger.Error("get owner failed", err, "kind", ref.Kind, "name", ref.Name)
+	}
+	owned = err == nil && owner.GetUID() == ref.UID && of.owns(owner, depth+1)
+
+	of.lock.Lock()
+	of.owned[ref.UID] = owned
+	of.lock.Unlock()
+	return owned
+}
+
+func (cc *CounterController) NewOwnerFilter(owner, namespace string) (*OwnerFilter, error) {
+	parts := strings.SplitN(owner, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid owner '%s', want <kind>/<name>", owner)
+	}
+
+	agvs, err := cc.serverResources()
+	if err != nil {
+		return nil, err
+	}
+	kind := strings.ToLower(parts[0])
+	var target *APIResourceGV
+	resources := map[schema.GroupKind]APIResourceGV{}
+	for i, agv := range agvs {
+		resources[schema.GroupKind{Group: agv.resource.Group, Kind: agv.resource.Kind}] = agv
+		if _, ok := agv.keys()[kind]; ok && target == nil {
+			target = &agvs[i]
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("kind '%s' of the owner not found", parts[0])
+	}
+
+	get := func(agv APIResourceGV, name, namespace string) (*unstructured.Unstructured, error) {
+		gvr := schema.GroupVersionResource{Group: agv.resource.Group, Version: agv.resource.Version, Resource: agv.resource.Name}
+		if !agv.resource.Namespaced {
+			return cc.dynamicClient.Resource(gvr).Get(cc.ctx, name, v1.GetOptions{})
+		}
+		return cc.dynamicClient.Resource(gvr).Namespace(namespace).Get(cc.ctx, name, v1.GetOptions{})
+	}
+
+	obj, err := get(*target, parts[1], namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OwnerFilter{
+		uid: obj.GetUID(),
+		get: func(ref v1.OwnerReference, namespace string) (*unstructured.Unstructured, error) {
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err != nil {
+				return nil, err
+			}
+			agv, ok := resources[schema.GroupKind{Group: gv.Group, Kind: ref.Kind}]
+			if !ok {
+				return nil, fmt.Errorf("kind '%s' of the owner not found", ref.Kind)
+			}
+			return get(agv, ref.Name, namespace)
+		},
+		owned: map[types.UID]bool{},
+	}, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+type Cluster struct {
+	Name       string `yaml:"name"`
+	Kubeconfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context"`
+}
+
+func (c Cluster) ConfigFlags() *genericclioptions.ConfigFlags {
+	return clusterConfigFlags(c.Kubeconfig, c.Context)
+}
+
+type Fleet struct {
+	Clusters []Cluster `yaml:"clusters"`
+}
+
+func loadKubeconfigDir(dir string) ([]Cluster, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusters []Cluster
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		clusters = append(clusters, Cluster{
+			Name:       strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			Kubeconfig: filepath.Join(dir, entry.Name()),
+		})
+	}
+	return clusters, nil
+}
+
+func loadFleetFile(path string) ([]Cluster, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fleet Fleet
+	if err := yaml.Unmarshal(b, &fleet); err != nil {
+		return nil, err
+	}
+
+	for i, cluster := range fleet.Clusters {
+		if cluster.Name == "" {
+			cluster.Name = cluster.Context
+		}
+		if cluster.Name == "" {
+			return nil, fmt.Errorf("cluster #%d in %s has neither name nor context", i, path)
+		}
+		if cluster.Kubeconfig != "" && !filepath.IsAbs(cluster.Kubeconfig) {
+			cluster.Kubeconfig = filepath.Join(filepath.Dir(path), cluster.Kubeconfig)
+		}
+		fleet.Clusters[i] = cluster
+	}
+	return fleet.Clusters, nil
+}
+
+func countCluster(cmd *cobra.Command, cluster Cluster, timeout time.Duration, kinds, order string, allNamespace bool) ([]Record, *CounterController, error) {
+	ctr, err := newController(cmd, cluster.ConfigFlags())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type result struct {
+		records []Record
+		err     error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		rs, err := ctr.Records(kinds, order, allNamespace)
+		ch <- result{records: rs, err: err}
+	}()
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		timer = time.After(timeout)
+	}
+
+	select {
+	case r := <-ch:
+		return r.records, ctr, r.err
+	case <-timer:
+		ctr.cancel()
+		return nil, nil, fmt.Errorf("timed out after %v", timeout)
+	}
+}
+
+func countClusters(cmd *cobra.Command, clusters []Cluster, workers int, timeout time.Duration, kinds, order string, allNamespace bool) ([]Record, map[string]error, *CounterController) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var last *CounterController
+	records := make([]Record, 0)
+	errs := map[string]error{}
+
+	sem := make(chan struct{}, workers)
+	for _, cluster := range clusters {
+		wg.Add(1)
+		go func(cluster Cluster) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			rs, ctr, err := countCluster(cmd, cluster, timeout, kinds, order, allNamespace)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				logger.Error("list resources failed", err, "cluster", cluster.Name)
+				errs[cluster.Name] = err
+				return
+			}
+			for i := range rs {
+				rs[i].Cluster = cluster.Name
+			}
+			records = append(records, rs...)
+			last = ctr
+		}(cluster)
+	}
+	wg.Wait()
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Cluster < records[j].Cluster
+	})
+	return records, errs, last
+}
+
+func summarizeClusters(total int, errs map[string]error) {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to count resources in cluster %s, error: %v\n", name, errs[name])
+	}
+	if len(names) > 0 {
+		fmt.Fprintf(os.Stderr, "[Summary] %d/%d clusters counted, failed: %s\n", total-len(names), total, strings.Join(names, ", "))
+	}
+}
+
+func fleetExitCode(errs map[string]error) int {
+	code := exitAccessDenied
+	for _, err := range errs {
+		if exitCode(err) != exitAccessDenied {
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+func RenderFleet(cmd *cobra.Command, clusters []Cluster, kinds, order, output string, allNamespace bool) {
+	if len(clusters) == 0 {
+		fmt.Fprintln(os.Stderr, "[Oh...] No clusters found!")
+		exit(exitFailure)
+	}
+
+	workers, _ := cmd.Flags().GetInt("workers")
+	timeout, _ := cmd.Flags().GetDuration("cluster-timeout")
+	records, errs, ctr := countClusters(cmd, clusters, workers, timeout, kinds, order, allNamespace)
+
+	if structuredOutput(output) {
+		if ctr == nil {
+			renderErrors(os.Stdout, output, clusterErrors(errs))
+			exit(fleetExitCode(errs))
+		}
+		ctr.errors = clusterErrors(errs)
+	} else {
+		summarizeClusters(len(clusters), errs)
+	}
+	if ctr == nil {
+		exit(fleetExitCode(errs))
+	}
+	ctr.output(records, output)
+	if len(errs) > 0 {
+		exit(exitPartial)
+	}
+}
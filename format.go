@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t))
+}
+
+func humanizeCount(n int, mode string) string {
+	switch mode {
+	case "comma", "c":
+		return commaCount(n)
+	case "short", "s":
+		return shortCount(n)
+	}
+	return strconv.Itoa(n)
+}
+
+func humanizeBytes(n uint64) string {
+	units := []string{"B", "Ki", "Mi", "Gi", "Ti"}
+	f, i := float64(n), 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%dB", n)
+	}
+	return fmt.Sprintf("%.1f%s", f, units[i])
+}
+
+func commaCount(n int) string {
+	s := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+
+	var b []byte
+	for i := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b = append(b, ',')
+		}
+		b = append(b, s[i])
+	}
+	return sign + string(b)
+}
+
+func shortCount(n int) string {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+
+	units := []struct {
+		value  int
+		suffix string
+	}{
+		{1000000000, "G"},
+		{1000000, "M"},
+		{1000, "k"},
+	}
+	for _, unit := range units {
+		// values that round up to the next unit are promoted, e.g. 999950 is 1M rather than 1000k.
+		if float64(abs) >= float64(unit.value)*0.99995 {
+			s := strconv.FormatFloat(float64(n)/float64(unit.value), 'f', 1, 64)
+			if len(s) > 2 && s[len(s)-2:] == ".0" {
+				s = s[:len(s)-2]
+			}
+			return fmt.Sprintf("%s%s", s, unit.suffix)
+		}
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestCommaCount(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 0, want: "0"},
+		{n: 999, want: "999"},
+		{n: 1000, want: "1,000"},
+		{n: 123456, want: "123,456"},
+		{n: 1234567, want: "1,234,567"},
+		{n: -1234567, want: "-1,234,567"},
+		{n: -100, want: "-100"},
+	}
+
+	for _, tt := range tests {
+		if got := commaCount(tt.n); got != tt.want {
+			t.Errorf("commaCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestShortCount(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 0, want: "0"},
+		{n: 999, want: "999"},
+		{n: 1000, want: "1k"},
+		{n: 1500, want: "1.5k"},
+		{n: 999949, want: "999.9k"},
+		{n: 999950, want: "1M"},
+		{n: 1234567, want: "1.2M"},
+		{n: 2500000000, want: "2.5G"},
+		{n: -1500, want: "-1.5k"},
+		{n: -999950, want: "-1M"},
+	}
+
+	for _, tt := range tests {
+		if got := shortCount(tt.n); got != tt.want {
+			t.Errorf("shortCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const metricName = "kubectl_count_resources"
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+type grafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+func (cc *CounterController) grafanaRender(w io.Writer, records []Record) {
+	breakdownColumns, metricColumns := cc.columns(records)
+
+	columns := []grafanaColumn{
+		{Text: "time", Type: "time"},
+		{Text: "cluster", Type: "string"},
+		{Text: "namespace", Type: "string"},
+		{Text: "groupVersion", Type: "string"},
+		{Text: "kind", Type: "string"},
+	}
+	for _, column := range breakdownColumns {
+		columns = append(columns, grafanaColumn{Text: column, Type: "string"})
+	}
+	columns = append(columns, grafanaColumn{Text: "count", Type: "number"})
+	for _, column := range metricColumns {
+		columns = append(columns, grafanaColumn{Text: column, Type: "number"})
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	rows := make([][]interface{}, 0, len(records))
+	for _, record := range records {
+		row := []interface{}{now, record.Cluster, record.Namespace, record.GroupVersion, record.Kind}
+		for _, column := range breakdownColumns {
+			row = append(row, record.Breakdown[column])
+		}
+		row = append(row, record.Count)
+		for _, column := range metricColumns {
+			row = append(row, record.Metrics[column])
+		}
+		rows = append(rows, row)
+	}
+
+	cc.jsonRender(w, []grafanaTable{{Type: "table", Columns: columns, Rows: rows}})
+}
+
+func (cc *CounterController) grafanaDashboardRender(w io.Writer, records []Record) {
+	var panels []map[string]interface{}
+	seen := map[string]bool{}
+	for _, record := range records {
+		id := record.Kind + "+" + record.GroupVersion
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		n := len(panels)
+		panels = append(panels, map[string]interface{}{
+			"id":         n + 1,
+			"type":       "timeseries",
+			"title":      fmt.Sprintf("%s (%s)", record.Kind, record.GroupVersion),
+			"datasource": map[string]string{"type": "prometheus", "uid": "${datasource}"},
+			"gridPos":    map[string]int{"h": 8, "w": 12, "x": (n % 2) * 12, "y": (n / 2) * 8},
+			"targets": []map[string]interface{}{{
+				"refId":        "A",
+				"expr":         fmt.Sprintf(`sum by (namespace) (%s{kind=%q, group_version=%q})`, metricName, record.Kind, record.GroupVersion),
+				"legendFormat": "{{namespace}}",
+			}},
+		})
+	}
+
+	cc.jsonRender(w, map[string]interface{}{
+		"title":         "kubectl-count",
+		"uid":           "kubectl-count",
+		"schemaVersion": 36,
+		"time":          map[string]string{"from": "now-24h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []map[string]interface{}{{
+				"name":  "datasource",
+				"type":  "datasource",
+				"query": "prometheus",
+			}},
+		},
+		"panels": panels,
+	})
+}
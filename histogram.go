@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+const histogramWidth = 40
+
+var histogramLayouts = map[string]string{
+	"hour": "2006-01-02 15:00",
+	"day":  "2006-01-02",
+}
+
+type HistogramBucket struct {
+	Created string `json:"created" yaml:"created"`
+	Count   int    `json:"count" yaml:"count"`
+}
+
+type CreationMap struct {
+	lock sync.Mutex
+	m    map[types.UID]time.Time
+}
+
+func NewCreationMap() *CreationMap {
+	return &CreationMap{
+		m: map[types.UID]time.Time{},
+	}
+}
+
+func (cm *CreationMap) observe(obj interface{}) {
+	o, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	cm.m[o.GetUID()] = o.GetCreationTimestamp().Time
+}
+
+func (cm *CreationMap) Handler(id string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: cm.observe,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			cm.observe(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			o, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			cm.lock.Lock()
+			defer cm.lock.Unlock()
+			delete(cm.m, o.GetUID())
+		},
+	}
+}
+
+func (cm *CreationMap) GetBuckets(unit, order string) []HistogramBucket {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
+	// buckets are in local time, "last tuesday" is a local day.
+	layout := histogramLayouts[unit]
+	counts := map[string]int{}
+	for _, created := range cm.m {
+		if created.IsZero() {
+			continue
+		}
+		counts[created.Local().Format(layout)]++
+	}
+
+	ret := make([]HistogramBucket, 0, len(counts))
+	for created, count := range counts {
+		ret = append(ret, HistogramBucket{Created: created, Count: count})
+	}
+
+	// the layouts sort chronologically as strings, the most recent buckets
+	// come first in descending order.
+	desc := isDesc(order)
+	sort.Slice(ret, func(i, j int) bool {
+		if desc {
+			return ret[i].Created > ret[j].Created
+		}
+		return ret[i].Created < ret[j].Created
+	})
+	return ret
+}
+
+func (cc *CounterController) creations(s string) (*CreationMap, error) {
+	defer cc.cancel()
+
+	creationMap := NewCreationMap()
+	if _, err := cc.sync(s, creationMap.Handler); err != nil && !errors.Is(err, errInterrupted) {
+		return nil, err
+	}
+	return creationMap, nil
+}
+
+func (cc *CounterController) histogramTableRender(w io.Writer, buckets []HistogramBucket) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"Created", "Count", "Histogram"})
+	table.SetAutoFormatHeaders(false)
+	table.SetRowLine(true)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+
+	peak := 0
+	for _, bucket := range buckets {
+		if bucket.Count > peak {
+			peak = bucket.Count
+		}
+	}
+	for _, bucket := range buckets {
+		// a non-empty bucket always gets a bar, however small.
+		bar := bucket.Count * histogramWidth / peak
+		if bar == 0 {
+			bar = 1
+		}
+		table.Append([]string{
+			bucket.Created,
+			humanizeCount(bucket.Count, cc.humanize),
+			strings.Repeat("█", bar),
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderHistogram(kinds, unit, order, output string) {
+	creationMap, err := cc.creations(kinds)
+	cc.summarize()
+	if err != nil {
+		logger.Error("list resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	buckets := creationMap.GetBuckets(unit, order)
+	if len(buckets) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("HistogramBucketList", buckets))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("HistogramBucketList", buckets))
+	default:
+		cc.histogramTableRender(&buf, buckets)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// matches of a query listed by the prompt used without fzf.
+const promptMatches = 20
+
+type kindCandidate struct {
+	name         string
+	kind         string
+	groupVersion string
+}
+
+func (kc kindCandidate) line() string {
+	return fmt.Sprintf("%s\t%s\t%s", kc.name, kc.kind, kc.groupVersion)
+}
+
+func (cc *CounterController) kindCandidates() ([]kindCandidate, error) {
+	agvs, err := cc.serverResources()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []kindCandidate
+	for _, agv := range agvs {
+		r := agv.resource
+		if !agv.listable() || strings.Contains(r.Name, "/") {
+			continue
+		}
+		name := r.Name
+		if r.Group != "" {
+			name = r.Name + "." + r.Group
+		}
+		candidates = append(candidates, kindCandidate{name: name, kind: r.Kind, groupVersion: agv.groupVersion})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].name < candidates[j].name })
+	return candidates, nil
+}
+
+// pickKinds lets users pick the kinds among the discovered ones with fzf,
+// or with a prompt filtering them the same way when fzf isn't installed.
+func (cc *CounterController) pickKinds() (string, error) {
+	candidates, err := cc.kindCandidates()
+	if err != nil {
+		return "", err
+	}
+
+	var picked []string
+	if path, err := exec.LookPath("fzf"); err == nil {
+		picked, err = fzfPick(path, candidates)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		picked = promptPick(os.Stdin, os.Stderr, candidates)
+	}
+	return argsKinds(picked), nil
+}
+
+func fzfPick(path string, candidates []kindCandidate) ([]string, error) {
+	var in, out bytes.Buffer
+	for _, candidate := range candidates {
+		fmt.Fprintln(&in, candidate.line())
+	}
+
+	cmd := exec.Command(path, "--multi", "--delimiter", "\t", "--prompt", "kinds> ", "--header", "TAB to pick, ENTER to count")
+	cmd.Stdin = &in
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		// 130 means fzf was left without picking anything.
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 130 {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var picked []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if fields := strings.Split(line, "\t"); fields[0] != "" {
+			picked = append(picked, fields[0])
+		}
+	}
+	return picked, nil
+}
+
+// fuzzyMatch tells whether the letters of the query appear in order in s.
+func fuzzyMatch(s, query string) bool {
+	s, query = strings.ToLower(s), strings.ToLower(query)
+	for _, r := range query {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+1:]
+	}
+	return true
+}
+
+func promptPick(r io.Reader, w io.Writer, candidates []kindCandidate) []string {
+	fmt.Fprintln(w, "[Interactive] fzf isn't installed, type a query to search the kinds, the numbers of the matches to pick or unpick them and an empty line to count the picked kinds")
+
+	var picked []string
+	isPicked := map[string]bool{}
+	matches := candidates
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(w, "kinds (%d picked)> ", len(picked))
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			break
+		}
+
+		if numbers, ok := parseNumbers(line); ok {
+			for _, n := range numbers {
+				if n < 1 || n > len(matches) || n > promptMatches {
+					fmt.Fprintf(w, "[Interactive] %d isn't listed\n", n)
+					continue
+				}
+				name := matches[n-1].name
+				isPicked[name] = !isPicked[name]
+				picked = picked[:0]
+				for _, candidate := range candidates {
+					if isPicked[candidate.name] {
+						picked = append(picked, candidate.name)
+					}
+				}
+			}
+			continue
+		}
+
+		matches = matches[:0:0]
+		for _, candidate := range candidates {
+			if fuzzyMatch(candidate.line(), line) {
+				matches = append(matches, candidate)
+			}
+		}
+		for i, candidate := range matches {
+			if i == promptMatches {
+				fmt.Fprintf(w, "      %d more, refine the query\n", len(matches)-i)
+				break
+			}
+			mark := " "
+			if isPicked[candidate.name] {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s %3d %s (%s %s)\n", mark, i+1, candidate.name, candidate.kind, candidate.groupVersion)
+		}
+	}
+	return picked
+}
+
+func parseNumbers(line string) ([]int, bool) {
+	var numbers []int
+	for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, len(numbers) > 0
+}
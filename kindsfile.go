@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// kinds counted without any argument, KUBECTL_COUNT_KINDS replaces them.
+const defaultKinds = "pods,deployments,statefulsets,daemonsets,jobs,services,configmaps,secrets"
+
+func fallbackKinds() string {
+	if kinds := argsKinds(strings.Fields(os.Getenv("KUBECTL_COUNT_KINDS"))); kinds != "" {
+		return kinds
+	}
+	return defaultKinds
+}
+
+// kindsArgs requires the kinds as arguments unless --kinds-file lists them.
+func kindsArgs(cmd *cobra.Command, args []string) error {
+	if file, _ := cmd.Flags().GetString("kinds-file"); file != "" {
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// loadKindsFile reads kinds split by lines, commas or spaces, # starts a comment.
+func loadKindsFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var fields []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields = append(fields, strings.Fields(line)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return argsKinds(fields), nil
+}
+
+// mustKinds merges the kinds of the arguments with the ones of --kinds-file.
+func mustKinds(cmd *cobra.Command, args []string) string {
+	kinds := argsKinds(args)
+	file, _ := cmd.Flags().GetString("kinds-file")
+	if file == "" {
+		return kinds
+	}
+
+	fileKinds, err := loadKindsFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to load kinds file %s, error: %v", file, err)
+		exit(exitFailure)
+	}
+	if fileKinds == "" {
+		fmt.Fprintf(os.Stderr, "[Oh...] Kinds file %s doesn't list any kind!\n", file)
+		exit(exitFailure)
+	}
+	return argsKinds([]string{kinds, fileKinds})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadKindsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "lines", content: "pods\ndeployments\n", want: "pods,deployments"},
+		{name: "commas and spaces", content: "pods, deploy\nsvc cm,secrets\n", want: "pods,deploy,svc,cm,secrets"},
+		{name: "comments", content: "# workloads\npods # running ones\n\n  #deploy\nsts\n", want: "pods,sts"},
+		{name: "duplicates", content: "pods\npods,svc\n", want: "pods,svc"},
+		{name: "empty", content: "# nothing yet\n", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kinds")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadKindsFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("loadKindsFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadKindsFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("loadKindsFile() of a missing file didn't fail")
+	}
+}
@@ -1,28 +1,41 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"os"
+	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
 	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/metadata"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -31,110 +44,648 @@ import (
 const (
 	resyncPeriod = time.Minute * 5
 	version      = "0.2.6"
+
+	// informers failing that many lists before syncing fall back to a paged list.
+	syncFailuresBeforeFallback = 3
+
+	exitFailure      = 1
+	exitPartial      = 2
+	exitAccessDenied = 3
+	exitEmpty        = 4
+	exitDrift        = 5
+	exitWarning      = 6
+	exitCritical     = 7
+	exitInterrupted  = 130
+)
+
+var (
+	errInterrupted  = errors.New("interrupted before all caches synced")
+	errAccessDenied = errors.New("access denied")
 )
 
 var cf = genericclioptions.NewConfigFlags(true)
 
 var rootCmd *cobra.Command
 
+func countArgs(cmd *cobra.Command, args []string) error {
+	if apiResources, _ := cmd.Flags().GetBool("api-resources"); apiResources {
+		return cobra.NoArgs(cmd, args)
+	}
+	// kinds default to the ones of the baseline, the thresholds or fallbackKinds.
+	return cobra.ArbitraryArgs(cmd, args)
+}
+
+func setup(cmd *cobra.Command, args []string) {
+	if verbosity, _ := strconv.Atoi(cmd.Flags().Lookup("v").Value.String()); verbosity <= 0 {
+		klog.SetOutput(io.Discard)
+		klog.LogToStderr(false)
+	}
+
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	logFile, _ := cmd.Flags().GetString("log-file")
+	if err := setupLogger(logFormat, logFile); err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to setup logger, error: %v", err)
+		exit(exitFailure)
+	}
+
+	if err := mergeKubeconfigs(); err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to merge kubeconfig files, error: %v", err)
+		exit(exitFailure)
+	}
+
+	profile, _ := cmd.Flags().GetString("profile")
+	profileOutput, _ := cmd.Flags().GetString("profile-output")
+	if err := startProfile(profile, profileOutput); err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to start profiling, error: %v", err)
+		exit(exitFailure)
+	}
+}
+
+func mustController(cmd *cobra.Command) *CounterController {
+	ctr, err := newController(cmd, cf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to Create Controller, error: %v", err)
+		exit(exitFailure)
+	}
+	return ctr
+}
+
+func runCount(cmd *cobra.Command, args []string) {
+	order, _ := cmd.Flags().GetString("order")
+	format, _ := cmd.Flags().GetString("output-format")
+	if apiResources, _ := cmd.Flags().GetBool("api-resources"); apiResources {
+		mustController(cmd).RenderAPIGroups(order, format)
+		exit(0)
+	}
+
+	allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+	// namespaces are summed up by the label or annotations with -A, split by their values.
+	label, _ := cmd.Flags().GetString("aggregate-by-namespace-label")
+	annotations, _ := cmd.Flags().GetStringSlice("attribute-by-annotation")
+	if label != "" || len(annotations) > 0 {
+		allNamespace = true
+	}
+	if baseline, _ := cmd.Flags().GetString("baseline"); baseline != "" {
+		// kinds default to the ones listed in the baseline.
+		mustController(cmd).RenderBaseline(baseline, mustKinds(cmd, args), format)
+		exit(0)
+	}
+
+	kinds := mustKinds(cmd, args)
+	if path, _ := cmd.Flags().GetString("thresholds"); kinds == "" && path != "" {
+		// kinds default to the ones limited by the thresholds.
+		thresholds, err := loadThresholds(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to load thresholds %s, error: %v", path, err)
+			exit(exitFailure)
+		}
+		kinds = thresholds.kinds()
+	}
+	if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+		picked, err := mustController(cmd).pickKinds()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to discover resources, error: %v", err)
+			exit(exitCode(err))
+		}
+		if picked == "" && kinds == "" {
+			fmt.Fprintln(os.Stderr, "[Oh...] No kind picked!")
+			exit(exitFailure)
+		}
+		kinds = argsKinds([]string{kinds, picked})
+	}
+	if kinds == "" {
+		kinds = fallbackKinds()
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		mustController(cmd).RenderDryRun(kinds, format)
+		exit(0)
+	}
+	if allContexts, _ := cmd.Flags().GetBool("all-contexts"); allContexts {
+		RenderContexts(cmd, kinds, order, format, allNamespace)
+		exit(0)
+	}
+	if dir, _ := cmd.Flags().GetString("kubeconfig-dir"); dir != "" {
+		clusters, err := loadKubeconfigDir(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to load kubeconfig directory, error: %v", err)
+			exit(exitFailure)
+		}
+		RenderFleet(cmd, clusters, kinds, order, format, allNamespace)
+		exit(0)
+	}
+	if file, _ := cmd.Flags().GetString("fleet"); file != "" {
+		clusters, err := loadFleetFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to load fleet file, error: %v", err)
+			exit(exitFailure)
+		}
+		RenderFleet(cmd, clusters, kinds, order, format, allNamespace)
+		exit(0)
+	}
+
+	ctr := mustController(cmd)
+	if churn, _ := cmd.Flags().GetBool("churn"); churn {
+		window, _ := cmd.Flags().GetDuration("window")
+		ctr.RenderChurn(kinds, order, format, allNamespace, window)
+		exit(0)
+	}
+	if bySubject, _ := cmd.Flags().GetBool("by-subject"); bySubject {
+		ctr.RenderSubjects(kinds, order, format)
+		exit(0)
+	}
+	if kindsPerNamespace, _ := cmd.Flags().GetBool("kinds-per-namespace"); kindsPerNamespace {
+		ctr.RenderNamespaceKinds(kinds, order, format)
+		exit(0)
+	}
+	if duplicateNames, _ := cmd.Flags().GetBool("duplicate-names"); duplicateNames {
+		ctr.RenderDuplicates(kinds, order, format)
+		exit(0)
+	}
+	if unit, _ := cmd.Flags().GetString("created-histogram"); unit != "" {
+		if _, ok := histogramLayouts[unit]; !ok {
+			fmt.Fprintf(os.Stderr, "[Oh...] Unknown histogram unit '%s', use hour or day!\n", unit)
+			exit(exitFailure)
+		}
+		ctr.RenderHistogram(kinds, unit, order, format)
+		exit(0)
+	}
+	if ttl, _ := cmd.Flags().GetDuration("cached"); ttl > 0 {
+		// the cache keeps the counts only, not the scope, ages and sync timings.
+		if format == "wide" || format == "w" {
+			fmt.Fprintln(os.Stderr, "[Oh...] --cached can't be used with -o wide!")
+			exit(exitFailure)
+		}
+		ctr.RenderCached(cmd, kinds, order, format, allNamespace, ttl)
+		exit(0)
+	}
+	ctr.Render(kinds, order, format, allNamespace)
+	exit(0)
+}
+
+var countCmd = &cobra.Command{
+	Use:   "count [kinds]",
+	Short: "Count resources by kind, the default command.",
+	Example: `  # same as kubectl count pods,ds,deploy
+  kubectl count count pods,ds,deploy`,
+	Args: countArgs,
+	Run:  runCount,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export <kinds>",
+	Short: "Count resources by kind and export them in a machine-readable format.",
+	Example: `  # export pods and deployments counts of every namespace into a parquet file.
+  kubectl count export -A -o parquet --output-file counts.parquet pods,deploy`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		order, _ := cmd.Flags().GetString("order")
+		format, _ := cmd.Flags().GetString("output-format")
+		if !cmd.Flags().Changed("output-format") {
+			format = "json"
+		}
+		if !machineReadable(format) {
+			fmt.Fprintf(os.Stderr, "[Oh...] Format %s can't be exported, use one of json, yaml, xlsx, parquet, sql, grafana or grafana-dashboard!\n", format)
+			exit(exitFailure)
+		}
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+		mustController(cmd).Render(mustKinds(cmd, args), order, format, allNamespace)
+		exit(0)
+	},
+}
+
+// addClusterFlags adds the flags connecting to a cluster and tuning the sync.
+func addClusterFlags(fs *pflag.FlagSet) {
+	fs.Duration("cache-ttl", defaultCacheTTL, "how long the discovery cache under --cache-dir is considered fresh")
+	fs.Bool("strict-discovery", false, "if present, fail when the discovery of an API group fails instead of counting the kinds of the other groups")
+	fs.Bool("strict", false, "if present, fail on warnings like kinds not found, failed discovery groups or approximate counts instead of printing the counts")
+	fs.Bool("no-cache", false, "if present, ignore the discovery cache and rediscover the server resources")
+	fs.Bool("no-protobuf", false, "if present, list and watch built-in kinds as JSON instead of protobuf")
+	fs.String("memory-limit", "", "if present, count the kinds not synced yet with paged lists instead of informers once the memory in use exceeds the limit, e.g. 500Mi")
+	fs.Int("max-concurrent", 0, "maximum number of kinds synced simultaneously, 0 means unlimited")
+	fs.Duration("per-kind-timeout", 0, "if present, skip a kind with a warning when it doesn't sync within the duration and count the others, e.g. 20s")
+	fs.Int("max-retries", 5, "times a GET is retried with exponential backoff on 5xx responses and network errors, 0 disables retries")
+	fs.Bool("show-timing", false, "if present, report discovery time, per-kind sync duration and objects processed to stderr")
+	cf.AddFlags(fs)
+	kubeconfig := fs.Lookup("kubeconfig")
+	kubeconfig.Value = &kubeconfigValue{p: cf.KubeConfig}
+	kubeconfig.Usage = "path to the kubeconfig file, repeat it or separate the paths like KUBECONFIG to merge several files"
+}
+
+func addOutputFlags(fs *pflag.FlagSet) {
+	fs.BoolP("all-namespaces", "A", false, "if present, resources aggregated by all namespaces")
+	fs.StringP("order", "O", "asc", "used to sort the counts in ascending or descending order. [asc(a)|desc(d)]")
+	fs.StringP("output-format", "o", "table", "output format. [grafana|grafana-dashboard|json(j)|parquet|sql|table(t)|wide(w)|xlsx|yaml(y)]")
+	fs.String("output-file", "", "if present, write the output to the file instead of stdout")
+	fs.String("humanize", "", "format counts in table output with thousands separators or abbreviations. [comma(c)|short(s)]")
+	fs.Lookup("humanize").NoOptDefVal = "comma"
+	fs.String("table-style", "grid", "style of the tables. [grid|rounded|borderless|plain]")
+	fs.Bool("no-merge-cells", false, "if present, repeat the identical cells of adjacent rows in tables instead of merging them")
+	fs.Bool("compact", false, "if present, drop the lines between the rows of tables and repeat the identical cells of adjacent rows, plain tables are padded by a single space")
+	fs.Int("max-col-width", 0, "if present, cut the cells of tables wider than the width with an ellipsis, e.g. long CRD groups, 0 means unlimited")
+	fs.Bool("no-pager", false, "if present, never pipe long output into $PAGER")
+}
+
+func addKindsFlags(fs *pflag.FlagSet) {
+	fs.String("kinds-file", "", "if present, count the kinds listed in the file as well, split by lines, commas or spaces, # starts a comment")
+}
+
+func addFilterFlags(fs *pflag.FlagSet) {
+	fs.String("name", "", "if present, only count objects whose whole name matches the regex")
+	fs.String("missing-label", "", "if present, only count objects without the label key, e.g. app.kubernetes.io/part-of")
+	fs.String("namespace-selector", "", "if present, only count objects in the namespaces matching the label selector, e.g. team=payments")
+	fs.String("owned-by", "", "if present, only count objects whose owner references lead to the object, e.g. deployment/my-app")
+}
+
+// addRecordFlags adds the flags shaping the counted records, the breakdowns
+// and analyzers are shared by the commands printing them.
+func addRecordFlags(fs *pflag.FlagSet) {
+	fs.String("sql-table", "kubectl_count", "table the INSERT statements of -o sql are written for")
+	fs.Bool("no-fail-on-empty", false, "if present, exit 0 instead of 4 when no resources are found")
+	fs.Bool("subtotals", false, "if present, add a row per namespace to table output with the counts of all kinds summed")
+	fs.Bool("by-platform", false, "if present, split nodes and the pods scheduled on them by the os and arch labels of the nodes")
+	fs.Bool("by-zone", false, "if present, split nodes and the pods scheduled on them by the region and zone labels of the nodes")
+	fs.StringSlice("by-node-label", nil, "if present, split nodes and the pods scheduled on them by the values of the node labels, e.g. a node pool label, labels split by comma")
+	fs.StringSlice("group-by-label", nil, "if present, split counts by the combination of the values of the labels, labels split by comma")
+	fs.Bool("node-conditions", false, "if present, split nodes by the conditions other than Ready they report, e.g. MemoryPressure")
+	fs.Duration("stale-jobs-after", 0, "if present, count jobs older than the duration that never completed or failed as stale")
+	fs.Bool("restarts", false, "if present, sum the container restarts of pods")
+	fs.Int("restarts-threshold", 0, "if present, also count pods whose containers restarted more times than the threshold, implies --restarts")
+	fs.StringSlice("require-labels", nil, "if present, count the objects missing any of the labels, labels split by comma")
+	fs.Bool("data-size", false, "if present, split configmaps and secrets by the size of their data. [<1KiB|<10KiB|<100KiB|>=100KiB]")
+	fs.Bool("ingress-rules", false, "if present, sum the hosts, rules and paths of ingresses and report the distinct hostnames")
+	fs.Bool("containers", false, "if present, sum the containers, init containers and running ephemeral containers of pods, average the containers per pod and count pods with 1, 2 and 3 or more containers")
+	fs.Bool("replicas", false, "if present, sum the desired, ready and available replicas of deployments, statefulsets, daemonsets and replicasets")
+	fs.Bool("breakdown", false, "if present, split counts of supported kinds by their status, e.g. events by type and reason, deployments by availability, jobs by completion, volumes by phase, nodes by readiness, webhook configurations by failure policy and scope, namespaces by phase")
+	fs.Bool("incremental", false, "if present, keep the last resourceVersion and the objects seen of every kind under --cache-dir and resume from them with a watch instead of relisting on the next run")
+	fs.Bool("managed-fields", false, "if present, report objects with bloated managedFields and their total managedFields size")
+	fs.Int("managed-fields-max-entries", 10, "managedFields entries above which an object is considered bloated, 0 disables the check")
+	fs.Int("managed-fields-max-bytes", 32768, "managedFields serialized bytes above which an object is considered bloated, 0 disables the check")
+}
+
+func addCountFlags(fs *pflag.FlagSet) {
+	fs.Duration("cached", 0, "if present, print the counts of a previous run with the same cluster, kinds and flags newer than the duration without accessing the cluster, e.g. 5m, not supported with -o wide")
+	fs.Bool("api-resources", false, "if present, count the resource types served by every API group version instead of objects")
+	fs.Bool("interactive", false, "if present, pick the kinds to count among the discovered ones with fzf, or with a prompt when fzf isn't installed")
+	fs.Bool("dry-run", false, "if present, print the resources each kind resolves to without counting them")
+	fs.Bool("all-contexts", false, "if present, count resources in every context of the kubeconfig")
+	fs.String("kubeconfig-dir", "", "if present, count resources in the current context of every kubeconfig file in the directory")
+	fs.String("fleet", "", "if present, count resources in every cluster listed in the fleet YAML file")
+	fs.Int("workers", 4, "number of clusters counted in parallel with --all-contexts, --kubeconfig-dir or --fleet")
+	fs.Duration("cluster-timeout", 2*time.Minute, "time allowed for counting a single cluster in multi-cluster mode, 0 means no timeout")
+	fs.String("textfile", "", "if present, atomically write the counts in Prometheus text format to the file for the node_exporter textfile collector")
+	fs.String("post-url", "", "if present, POST the counts as the JSON envelope of -o json to the URL after each run, e.g. https://inventory.internal/api/counts")
+	fs.String("post-token", "", "bearer token sent with --post-url, defaults to $"+postTokenEnv)
+	fs.StringArray("post-header", nil, "header sent with --post-url as '<name>: <value>', repeat the flag for more")
+	fs.Bool("churn", false, "if present, report adds, updates and deletes per minute observed during the window instead of totals")
+	fs.Duration("window", time.Minute, "how long to keep watching resources in churn mode")
+	fs.Bool("by-subject", false, "if present, count the rolebindings and clusterrolebindings of every user, group and serviceaccount instead of totals")
+	fs.String("aggregate-by-namespace-label", "", "if present, sum the counts of the namespaces sharing a value of the namespace label instead of listing every namespace, e.g. team")
+	fs.StringSlice("attribute-by-annotation", nil, "if present, sum the counts by the values of the namespace annotations instead of listing every namespace, e.g. an owner or cost center, objects without them are unattributed, annotations split by comma")
+	fs.Bool("show-empty-namespaces", false, "if present, list the namespaces without any of the kinds with a count of 0")
+	fs.Bool("kinds-per-namespace", false, "if present, report how many distinct kinds every namespace contains instead of totals, -o wide lists them")
+	fs.Bool("duplicate-names", false, "if present, report the names used in more than one namespace for every kind instead of totals")
+	fs.String("created-histogram", "", "if present, bucket objects by creation time [hour|day] and print a histogram instead of totals")
+	fs.String("baseline", "", "if present, compare the counts with the expected ones of the baseline file and exit 5 on drift")
+	fs.String("thresholds", "", "if present, color the counts over the warning and critical limits of the thresholds file and exit 6 or 7 when any is crossed")
+}
+
 func init() {
 	rootCmd = &cobra.Command{
-		Use:   "kubectl-count <kinds>",
+		Use:   "kubectl-count [kinds]",
 		Short: "Count resources by kind.",
-		Example: `  # display a table of specified resources counts, kinds split by comma.
+		Example: `  # display a table of specified resources counts, kinds split by comma or space.
   kubectl count pods,ds,deploy
+  kubectl count pods ds deploy
+
+  # count pods, workloads, services, configmaps and secrets, or the kinds of KUBECTL_COUNT_KINDS.
+  kubectl count
 
   # display kube-system namespace resources counts info in yaml format.
-  kubectl count -oy -n kube-system rs,ep`,
-		Version: version,
-		Args:    cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			klog.SetOutput(io.Discard)
-			klog.LogToStderr(false)
+  kubectl count -oy -n kube-system rs,ep
 
-			namespace, _ := cmd.Flags().GetString("namespace")
-			ctr, err := NewCounterController(namespace)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[Oh...] Failed to Create Controller, error: %v", err)
-				os.Exit(1)
-			}
+  # keep the counts of pods up to date as they change.
+  kubectl count watch pods`,
+		Version:          version,
+		Args:             countArgs,
+		PersistentPreRun: setup,
+		Run:              runCount,
+	}
+
+	// only the flags of every command are persistent, the others are added
+	// to the commands using them.
+	fs := rootCmd.PersistentFlags()
+	fs.String("log-format", "", "if present, log what the tool did to --log-file or stderr. [json|text]")
+	fs.String("log-file", "", "file the structured logs are appended to")
+	fs.String("profile", "", "if present, write a pprof profile of the run. [cpu|mem]")
+	fs.String("profile-output", "", "file the profile is written to, defaults to <profile>.pprof")
 
-			kinds := args[0]
-			order, _ := cmd.Flags().GetString("order")
-			format, _ := cmd.Flags().GetString("output-format")
-			allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
-			ctr.Render(kinds, order, format, allNamespace)
-		},
+	klogFlags := flag.NewFlagSet("klog", flag.ExitOnError)
+	klog.InitFlags(klogFlags)
+	verbosity := pflag.PFlagFromGoFlag(klogFlags.Lookup("v"))
+	verbosity.Shorthand = "v"
+	verbosity.Usage = "number for the log level verbosity, logs are discarded unless it is greater than 0"
+	fs.AddFlag(verbosity)
+
+	// diff compares two snapshots offline, but the live counts otherwise.
+	for _, cmd := range []*cobra.Command{rootCmd, countCmd, watchCmd, serveCmd, diffCmd, snapshotCmd, exportCmd, compareNamespacesCmd, compareCmd, compareAsCmd, coverageCmd, topNamespacesCmd, benchCmd, crdVersionsCmd, endpointSlicesCmd, explainCmd} {
+		addClusterFlags(cmd.Flags())
+		addOutputFlags(cmd.Flags())
+	}
+	for _, cmd := range []*cobra.Command{rootCmd, countCmd, watchCmd, serveCmd, snapshotCmd, exportCmd, compareNamespacesCmd, compareCmd, compareAsCmd, topNamespacesCmd, benchCmd, explainCmd} {
+		addKindsFlags(cmd.Flags())
 	}
+	for _, cmd := range []*cobra.Command{rootCmd, countCmd, watchCmd, serveCmd, diffCmd, snapshotCmd, exportCmd, compareNamespacesCmd, compareCmd, compareAsCmd, topNamespacesCmd, benchCmd} {
+		addFilterFlags(cmd.Flags())
+	}
+	for _, cmd := range []*cobra.Command{rootCmd, countCmd, watchCmd, serveCmd, diffCmd, snapshotCmd, exportCmd, compareNamespacesCmd, compareCmd, compareAsCmd} {
+		addRecordFlags(cmd.Flags())
+	}
+	addCountFlags(rootCmd.Flags())
+	addCountFlags(countCmd.Flags())
+
+	watchCmd.Flags().Duration("interval", 0, "refresh the display at most once per interval instead of on every change, e.g. 5s")
+	watchCmd.Flags().Bool("stream", false, "if present, write the counts as NDJSON records to stdout every interval, 10s by default, instead of refreshing the display")
+	serveCmd.Flags().String("listen-address", ":8080", "address the metrics server listens on")
+	serveCmd.Flags().Bool("external-metrics", false, "if present, also serve the counts through the external.metrics.k8s.io API over TLS so HPAs can scale on them")
+	serveCmd.Flags().String("tls-cert-file", "", "certificate served with --external-metrics, a self-signed one is generated if empty")
+	serveCmd.Flags().String("tls-private-key-file", "", "private key of --tls-cert-file")
+
+	compareCmd.Flags().StringSlice("contexts", nil, "contexts of the kubeconfig to compare, split by comma or repeated for every cluster")
+	compareCmd.Flags().Int("workers", 4, "number of clusters counted in parallel")
+	compareCmd.Flags().Duration("cluster-timeout", 2*time.Minute, "time allowed for counting a single cluster, 0 means no timeout")
+	snapshotCmd.Flags().String("upload", "", "if present, also upload the snapshot under a timestamped key with the aws or gcloud CLI, e.g. s3://bucket/path/ or gs://bucket/path/")
+	topNamespacesCmd.Flags().Int("top", 10, "number of namespaces ranked, 0 means all")
+	genAlertsCmd.Flags().String("rule-name", "kubectl-count", "name of the PrometheusRule and of its rule group")
+	genAlertsCmd.Flags().Duration("for", 10*time.Minute, "how long a limit has to be crossed before the alert fires, in whole seconds, 0 fires right away")
+	versionCmd.Flags().Bool("check", false, "if present, check GitHub for a newer release")
 
-	rootCmd.Flags().BoolP("all-namespaces", "A", false, "if present, resources aggregated by all namespaces")
-	rootCmd.Flags().StringP("order", "O", "asc", "used to sort the counts in ascending or descending order. [asc(a)|desc(d)]")
-	rootCmd.Flags().StringP("output-format", "o", "table", "output format. [json(j)|table(t)|yaml(y)]")
-	cf.AddFlags(rootCmd.Flags())
+	rootCmd.AddCommand(countCmd, watchCmd, serveCmd, diffCmd, snapshotCmd, exportCmd, compareNamespacesCmd, compareCmd, compareAsCmd, coverageCmd, topNamespacesCmd, benchCmd, genAlertsCmd, crdVersionsCmd, endpointSlicesCmd, explainCmd, schemaCmd, versionCmd, selfUpdateCmd)
 }
 
 type Record struct {
-	Namespace    string `json:"namespace" yaml:"namespace"`
-	GroupVersion string `json:"groupVersion" yaml:"groupVersion"`
-	Kind         string `json:"kind" yaml:"kind"`
-	Count        int    `json:"count" yaml:"count"`
+	Cluster      string             `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Namespace    string             `json:"namespace" yaml:"namespace"`
+	GroupVersion string             `json:"groupVersion" yaml:"groupVersion"`
+	Kind         string             `json:"kind" yaml:"kind"`
+	Breakdown    map[string]string  `json:"breakdown,omitempty" yaml:"breakdown,omitempty"`
+	Count        int                `json:"count" yaml:"count"`
+	Metrics      map[string]int     `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+	Averages     map[string]float64 `json:"averages,omitempty" yaml:"averages,omitempty"`
+	Change       *Change            `json:"change,omitempty" yaml:"change,omitempty"`
+	Approximate  bool               `json:"approximate,omitempty" yaml:"approximate,omitempty"`
+
+	Scope        string        `json:"-" yaml:"-"`
+	Oldest       time.Time     `json:"-" yaml:"-"`
+	Newest       time.Time     `json:"-" yaml:"-"`
+	Namespaces   int           `json:"-" yaml:"-"`
+	SyncDuration time.Duration `json:"-" yaml:"-"`
+	Processed    int           `json:"-" yaml:"-"`
+}
+
+func (r *Record) observeAge(oldest, newest time.Time) {
+	if !oldest.IsZero() && (r.Oldest.IsZero() || oldest.Before(r.Oldest)) {
+		r.Oldest = oldest
+	}
+	if newest.After(r.Newest) {
+		r.Newest = newest
+	}
+}
+
+type Sample struct {
+	Created   time.Time            `json:"created"`
+	Breakdown map[string]string    `json:"breakdown,omitempty"`
+	Metrics   map[string]int       `json:"metrics,omitempty"`
+	Deadlines map[string]time.Time `json:"deadlines,omitempty"`
+}
+
+func (s Sample) key() string {
+	if len(s.Breakdown) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(s.Breakdown))
+	for k := range s.Breakdown {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+s.Breakdown[k])
+	}
+	return strings.Join(parts, ",")
+}
+
+type Bucket struct {
+	Namespace string
+	Breakdown string
+}
+
+type Counter struct {
+	Count     int
+	Breakdown map[string]string
+	Metrics   map[string]int
+	// objects per deadline in unix nanoseconds, of every deadline metric.
+	Deadlines map[string]map[int64]int
+	Oldest    time.Time
+	Newest    time.Time
+}
+
+func (c *Counter) add(delta int, sample Sample) {
+	c.Count += delta
+	if delta > 0 && !sample.Created.IsZero() {
+		if c.Oldest.IsZero() || sample.Created.Before(c.Oldest) {
+			c.Oldest = sample.Created
+		}
+		if sample.Created.After(c.Newest) {
+			c.Newest = sample.Created
+		}
+	}
+	for k, v := range sample.Metrics {
+		if c.Metrics == nil {
+			c.Metrics = map[string]int{}
+		}
+		c.Metrics[k] += delta * v
+	}
+	for k, t := range sample.Deadlines {
+		if c.Deadlines == nil {
+			c.Deadlines = map[string]map[int64]int{}
+		}
+		if c.Deadlines[k] == nil {
+			c.Deadlines[k] = map[int64]int{}
+		}
+		c.Deadlines[k][t.UnixNano()] += delta
+		if c.Deadlines[k][t.UnixNano()] == 0 {
+			delete(c.Deadlines[k], t.UnixNano())
+		}
+	}
+}
+
+// metrics are the metrics of the counter with the deadlines passed by now.
+func (c *Counter) metrics(now time.Time) map[string]int {
+	metrics := copyMetrics(c.Metrics)
+	for k, deadlines := range c.Deadlines {
+		for deadline, n := range deadlines {
+			if deadline > now.UnixNano() {
+				continue
+			}
+			if metrics == nil {
+				metrics = map[string]int{}
+			}
+			metrics[k] += n
+		}
+	}
+	return metrics
 }
 
 type IDMap struct {
-	lock sync.Mutex
-	m    map[string]map[string]int
-	ids  []string
+	lock        sync.Mutex
+	m           map[string]map[Bucket]*Counter
+	ids         []string
+	scopes      map[string]string
+	dropped     map[string]bool
+	approximate map[string]bool
 }
 
 func NewIDMap() *IDMap {
 	return &IDMap{
-		m: map[string]map[string]int{},
+		m:           map[string]map[Bucket]*Counter{},
+		scopes:      map[string]string{},
+		dropped:     map[string]bool{},
+		approximate: map[string]bool{},
 	}
 }
 
 func (idm *IDMap) KindGroupVersion(id string) (string, string) {
+	return splitID(id)
+}
+
+func splitID(id string) (string, string) {
 	parts := strings.Split(id, "+")
 	return parts[0], parts[1]
 }
 
-func (idm *IDMap) Add(id, namespace string) {
+func (idm *IDMap) Add(id, namespace string, sample Sample) {
 	idm.lock.Lock()
 	defer idm.lock.Unlock()
 
+	if idm.dropped[id] {
+		return
+	}
 	if _, ok := idm.m[id]; !ok {
-		idm.m[id] = map[string]int{}
+		idm.m[id] = map[Bucket]*Counter{}
+	}
+	bucket := Bucket{Namespace: namespace, Breakdown: sample.key()}
+	if _, ok := idm.m[id][bucket]; !ok {
+		idm.m[id][bucket] = &Counter{Breakdown: sample.Breakdown}
 	}
-	idm.m[id][namespace]++
+	idm.m[id][bucket].add(1, sample)
 }
 
-func (idm *IDMap) Del(id, namespace string) {
+func (idm *IDMap) Del(id, namespace string, sample Sample) {
 	idm.lock.Lock()
 	defer idm.lock.Unlock()
 
-	if _, ok := idm.m[id]; !ok {
+	if _, ok := idm.m[id]; !ok || idm.dropped[id] {
+		return
+	}
+	bucket := Bucket{Namespace: namespace, Breakdown: sample.key()}
+	if _, ok := idm.m[id][bucket]; !ok {
 		return
 	}
-	idm.m[id][namespace]--
+	idm.m[id][bucket].add(-1, sample)
+}
+
+func (idm *IDMap) Drop(id string) {
+	idm.lock.Lock()
+	defer idm.lock.Unlock()
+
+	// events of the informer still in flight must not touch the counts which
+	// replace the ones of the informer.
+	idm.dropped[id] = true
+	delete(idm.m, id)
 }
 
-func (idm *IDMap) AddID(id string) {
+func (idm *IDMap) Replace(id string, from *IDMap) {
+	from.lock.Lock()
+	counters := from.m[id]
+	from.lock.Unlock()
+
+	idm.lock.Lock()
+	defer idm.lock.Unlock()
+	idm.m[id] = counters
+}
+
+func (idm *IDMap) Approximate(id string) {
+	idm.lock.Lock()
+	defer idm.lock.Unlock()
+
+	idm.approximate[id] = true
+}
+
+func (idm *IDMap) AddID(id string, namespaced bool) {
 	idm.ids = append(idm.ids, id)
+	idm.scopes[id] = "Cluster"
+	if namespaced {
+		idm.scopes[id] = "Namespaced"
+	}
+}
+
+func (idm *IDMap) AddEmptyNamespaces(namespaces []string) {
+	idm.lock.Lock()
+	defer idm.lock.Unlock()
+
+	used := map[string]bool{}
+	for _, counters := range idm.m {
+		for bucket, c := range counters {
+			if c.Count > 0 {
+				used[bucket.Namespace] = true
+			}
+		}
+	}
+	for _, namespace := range namespaces {
+		if used[namespace] {
+			continue
+		}
+		for _, id := range idm.ids {
+			if idm.scopes[id] != "Namespaced" || idm.dropped[id] && idm.m[id] == nil {
+				continue
+			}
+			if _, ok := idm.m[id]; !ok {
+				idm.m[id] = map[Bucket]*Counter{}
+			}
+			bucket := Bucket{Namespace: namespace}
+			if _, ok := idm.m[id][bucket]; !ok {
+				idm.m[id][bucket] = &Counter{}
+			}
+		}
+	}
 }
 
 func (idm *IDMap) GetRecords(order string, allNamespace bool) []Record {
 	idm.lock.Lock()
 	defer idm.lock.Unlock()
 
+	now := time.Now()
 	records := map[string][]Record{}
 	for id, counter := range idm.m {
 		kind, groupVersion := idm.KindGroupVersion(id)
 		rs := make([]Record, 0)
-		for ns, c := range counter {
+		for bucket, c := range counter {
+			if bucket.Breakdown != "" && c.Count == 0 {
+				continue
+			}
 			rs = append(rs, Record{
-				Namespace:    ns,
+				Namespace:    bucket.Namespace,
 				Kind:         kind,
 				GroupVersion: groupVersion,
-				Count:        c,
+				Breakdown:    c.Breakdown,
+				Count:        c.Count,
+				Metrics:      c.metrics(now),
+				Approximate:  idm.approximate[id],
+				Scope:        idm.scopes[id],
+				Oldest:       c.Oldest,
+				Newest:       c.Newest,
 			})
 		}
 		records[id] = rs
@@ -144,30 +695,44 @@ func (idm *IDMap) GetRecords(order string, allNamespace bool) []Record {
 	if allNamespace {
 		for id, counter := range records {
 			kind, _ := idm.KindGroupVersion(id)
-			r := Record{Kind: kind}
+			merged := map[string]*Record{}
+			namespaces := map[string]map[string]struct{}{}
+			var keys []string
 			for _, c := range counter {
+				key := Sample{Breakdown: c.Breakdown}.key()
+				r, ok := merged[key]
+				if !ok {
+					r = &Record{Kind: kind, Breakdown: c.Breakdown, Approximate: c.Approximate, Scope: c.Scope}
+					merged[key] = r
+					namespaces[key] = map[string]struct{}{}
+					keys = append(keys, key)
+				}
 				r.Count += c.Count
 				r.GroupVersion = c.GroupVersion
+				r.observeAge(c.Oldest, c.Newest)
+				if c.Namespace != "" && c.Count > 0 {
+					namespaces[key][c.Namespace] = struct{}{}
+					r.Namespaces = len(namespaces[key])
+				}
+				for k, v := range c.Metrics {
+					if r.Metrics == nil {
+						r.Metrics = map[string]int{}
+					}
+					r.Metrics[k] += v
+				}
+			}
+			for _, key := range keys {
+				tmp[id] = append(tmp[id], *merged[key])
 			}
-			tmp[id] = []Record{r}
 		}
 		records = tmp
 	}
 
-	order = strings.ToLower(order)
-	switch order {
-	case "desc", "d":
-		for _, rs := range records {
-			sort.Slice(rs, func(i, j int) bool {
-				return rs[i].Count > rs[j].Count
-			})
-		}
-	default:
-		for _, rs := range records {
-			sort.Slice(rs, func(i, j int) bool {
-				return rs[i].Count < rs[j].Count
-			})
-		}
+	desc := isDesc(order)
+	for _, rs := range records {
+		sort.SliceStable(rs, func(i, j int) bool {
+			return lessRecord(rs[i], rs[j], desc)
+		})
 	}
 
 	ret := make([]Record, 0)
@@ -177,129 +742,798 @@ func (idm *IDMap) GetRecords(order string, allNamespace bool) []Record {
 	return ret
 }
 
+func isDesc(order string) bool {
+	switch strings.ToLower(order) {
+	case "desc", "d":
+		return true
+	}
+	return false
+}
+
+func lessRecord(a, b Record, desc bool) bool {
+	if a.Count != b.Count {
+		if desc {
+			return a.Count > b.Count
+		}
+		return a.Count < b.Count
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	return Sample{Breakdown: a.Breakdown}.key() < Sample{Breakdown: b.Breakdown}.key()
+}
+
+func copyMetrics(metrics map[string]int) map[string]int {
+	if metrics == nil {
+		return nil
+	}
+	cloned := make(map[string]int, len(metrics))
+	for k, v := range metrics {
+		cloned[k] = v
+	}
+	return cloned
+}
+
 type CounterController struct {
 	ctx             context.Context
 	cancel          context.CancelFunc
 	discoveryClient discovery.CachedDiscoveryInterface
+	dynamicClient   dynamic.Interface
+	metadataClient  metadata.Interface
 	factory         dynamicinformer.DynamicSharedInformerFactory
+	protobuf        *protobufClients
+	namespace       string
+	bookmarks       string
+	memoryLimit     uint64
+	pressure        chan struct{}
+	analyzers       []Analyzer
+	classifiers     []Classifier
+	filters         []Filter
+	informers       map[string]cache.SharedIndexInformer
+	ingressRules    bool
+	nodeLabels      *NodeLabels
+	namespaceMeta   *NamespaceMeta
+	timing          *Timing
+	retryStats      *RetryStats
+	serverWarnings  *ServerWarnings
+	tableStyle      string
+	noMergeCells    bool
+	compact         bool
+	maxColWidth     int
+	showTiming      bool
+	humanize        string
+	noPager         bool
+	outputFile      string
+	postTarget      *PostTarget
+	uploadTarget    *UploadTarget
+	sqlTable        string
+	noFailOnEmpty   bool
+	textfile        string
+	subtotals       bool
+	maxConcurrent   int
+	strictDiscovery bool
+	emptyNamespaces bool
+	// nil unless --namespace-selector is set.
+	selectedNamespaces map[string]bool
+	thresholds         *Thresholds
+	breaches           []Breach
+	errors             []OutputError
+	strict             bool
+	perKindTimeout     time.Duration
+	warnings           []OutputWarning
+	warningsLock       sync.Mutex
+	discoveryWarned    sync.Once
+	interrupted        int32
+}
+
+type ControllerOptions struct {
+	ConfigFlags   *genericclioptions.ConfigFlags
+	Namespace     string
+	MaxRetries    int
+	CacheTTL      time.Duration
+	NoCache       bool
+	NoProtobuf    bool
+	MaxConcurrent int
 }
 
-func NewCounterController(namespace string) (*CounterController, error) {
-	restConfig, err := cf.ToRESTConfig()
+func NewCounterController(opts ControllerOptions) (*CounterController, error) {
+	if opts.ConfigFlags == nil {
+		opts.ConfigFlags = cf
+	}
+
+	restConfig, err := opts.ConfigFlags.ToRESTConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	restConfig.QPS = 1000
-	restConfig.Burst = 1000
+	retryStats := NewRetryStats()
+	limiter := newAdaptiveRateLimiter(maxQPS, retryStats)
+	restConfig.QPS = maxQPS
+	restConfig.Burst = maxQPS
+	restConfig.RateLimiter = observedRateLimiter{
+		RateLimiter: limiter,
+		stats:       retryStats,
+	}
+	serverWarnings := NewServerWarnings()
+	restConfig.WarningHandler = serverWarnings
+	restConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &retryRoundTripper{next: rt, maxRetries: opts.MaxRetries, stats: retryStats, limiter: limiter}
+	})
 
 	dyn, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	dc, err := cf.ToDiscoveryClient()
+	mc, err := metadata.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, err := newDiscoveryClient(opts.ConfigFlags, opts.CacheTTL, opts.NoCache)
 	if err != nil {
 		return nil, err
 	}
 
+	var protobuf *protobufClients
+	if !opts.NoProtobuf {
+		protobuf = newProtobufClients(restConfig)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	return &CounterController{
 		ctx:             ctx,
 		cancel:          cancel,
 		discoveryClient: dc,
-		factory:         dynamicinformer.NewFilteredDynamicSharedInformerFactory(dyn, resyncPeriod, namespace, nil),
+		dynamicClient:   dyn,
+		metadataClient:  mc,
+		factory:         dynamicinformer.NewFilteredDynamicSharedInformerFactory(dyn, resyncPeriod, opts.Namespace, nil),
+		protobuf:        protobuf,
+		namespace:       opts.Namespace,
+		timing:          NewTiming(),
+		retryStats:      retryStats,
+		serverWarnings:  serverWarnings,
+		maxConcurrent:   opts.MaxConcurrent,
 	}, nil
 }
 
-func (cc *CounterController) sanitizeKinds(s string) []string {
-	var kinds []string
-	for _, part := range strings.Split(s, ",") {
-		part = strings.TrimSpace(part)
-		if part != "" {
-			kinds = append(kinds, part)
-		}
-	}
-	return kinds
+func (cc *CounterController) Use(analyzers ...Analyzer) {
+	cc.analyzers = append(cc.analyzers, analyzers...)
 }
 
-func (cc *CounterController) list(s string) (*IDMap, error) {
-	kinds := cc.sanitizeKinds(s)
-	if len(kinds) == 0 {
-		return nil, fmt.Errorf("invalid input kind name: '%s'", s)
-	}
+func (cc *CounterController) HandleSignals() {
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		atomic.StoreInt32(&cc.interrupted, 1)
+		cc.cancel()
+		<-ch
+		exit(exitInterrupted)
+	}()
+}
 
-	apiResources, err := cc.getApiResources()
-	if err != nil {
-		return nil, err
+func (cc *CounterController) partial() bool {
+	if atomic.LoadInt32(&cc.interrupted) == 0 {
+		return false
 	}
+	fmt.Fprintln(os.Stderr, "[Oh...] Interrupted, the results above are partial!")
+	return true
+}
 
-	idMap := NewIDMap()
-	informers := map[string]cache.SharedIndexInformer{}
-	for _, kind := range kinds {
-		if ars, ok := apiResources[kind]; ok {
-			for _, ar := range ars {
-				informers[ar.ID()] = cc.factory.ForResource(schema.GroupVersionResource{
-					Group:    ar.resource.Group,
-					Version:  ar.resource.Version,
-					Resource: ar.resource.Name,
-				}).Informer()
-				idMap.AddID(ar.ID())
-			}
-		}
-	}
+func (cc *CounterController) ShowTiming(show bool) {
+	cc.showTiming = show
+}
 
-	if len(informers) == 0 {
-		return nil, errors.New("no available informers found")
-	}
+func (cc *CounterController) Humanize(mode string) {
+	cc.humanize = mode
+}
 
-	for id, informer := range informers {
-		cloned := id
-		informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {})
-		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				o, ok := obj.(*unstructured.Unstructured)
-				if !ok {
-					return
-				}
-				idMap.Add(cloned, o.GetNamespace())
-			},
-			DeleteFunc: func(obj interface{}) {
-				o, ok := obj.(*unstructured.Unstructured)
-				if !ok {
-					return
-				}
-				idMap.Del(cloned, o.GetNamespace())
-			},
-		})
-		go informer.Run(cc.ctx.Done())
-	}
+func (cc *CounterController) DisablePager(disabled bool) {
+	cc.noPager = disabled
+}
 
-	for kind, informer := range informers {
-		if !cache.WaitForNamedCacheSync(kind, cc.ctx.Done(), informer.HasSynced) {
-			return nil, fmt.Errorf("failed to sync %s cache", kind)
-		}
-	}
+func (cc *CounterController) OutputFile(path string) {
+	cc.outputFile = path
+}
 
-	cc.cancel()
-	return idMap, nil
+func (cc *CounterController) SQLTable(table string) {
+	cc.sqlTable = table
 }
 
-type APIResourceGV struct {
-	resource     v1.APIResource
-	groupVersion string
+func (cc *CounterController) NoFailOnEmpty(enabled bool) {
+	cc.noFailOnEmpty = enabled
 }
 
-func (agv APIResourceGV) ID() string {
-	return agv.resource.Kind + "+" + agv.groupVersion
+func (cc *CounterController) Textfile(path string) {
+	cc.textfile = path
 }
 
-func (cc *CounterController) getApiResources() (map[string][]APIResourceGV, error) {
-	resources, _ := cc.discoveryClient.ServerPreferredResources()
-	rm := make(map[string][]APIResourceGV)
-	for _, resource := range resources {
-		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
-		if err != nil {
+func (cc *CounterController) Subtotals(enabled bool) {
+	cc.subtotals = enabled
+}
+
+func (cc *CounterController) IngressRules(enabled bool) {
+	cc.ingressRules = enabled
+	if enabled {
+		cc.Use(IngressAnalyzer{})
+	}
+}
+
+func machineReadable(output string) bool {
+	switch output {
+	case "json", "j", "yaml", "y", "xlsx", "parquet", "sql", "grafana", "grafana-dashboard":
+		return true
+	}
+	return false
+}
+
+func (cc *CounterController) empty(output string) {
+	w := os.Stdout
+	if machineReadable(output) {
+		w = os.Stderr
+	}
+	fmt.Fprintln(w, "[Oh...] No Resources found!")
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+	if !cc.noFailOnEmpty {
+		exit(exitEmpty)
+	}
+}
+
+func (cc *CounterController) summarize() {
+	if cc.showTiming {
+		cc.timing.Report(os.Stderr)
+	}
+	cc.retryStats.Report(os.Stderr)
+	cc.reportServerWarnings(os.Stderr)
+	if cc.ingressRules {
+		cc.reportIngressHosts(os.Stderr)
+	}
+	cc.reportTerminatingNamespaces(os.Stderr)
+}
+
+func (cc *CounterController) reportTerminatingNamespaces(w io.Writer) {
+	var terminating []*unstructured.Unstructured
+	for id, informer := range cc.informers {
+		if kind, _ := splitID(id); kind != "Namespace" {
+			continue
+		}
+		for _, obj := range informer.GetStore().List() {
+			o, ok := obj.(*unstructured.Unstructured)
+			if !ok || !cc.match(o) {
+				continue
+			}
+			if phase, _, _ := unstructured.NestedString(o.Object, "status", "phase"); phase == "Terminating" {
+				terminating = append(terminating, o)
+			}
+		}
+	}
+	if len(terminating) <= 0 {
+		return
+	}
+
+	// the ones stuck the longest come first.
+	sort.Slice(terminating, func(i, j int) bool {
+		return deletedAt(terminating[i]).Before(deletedAt(terminating[j]))
+	})
+	names := make([]string, 0, len(terminating))
+	for _, o := range terminating {
+		names = append(names, fmt.Sprintf("%s (terminating for %s)", o.GetName(), age(deletedAt(o))))
+	}
+	fmt.Fprintf(w, "[Namespace] %d terminating namespaces: %s\n", len(terminating), strings.Join(names, ", "))
+}
+
+func deletedAt(obj *unstructured.Unstructured) time.Time {
+	if t := obj.GetDeletionTimestamp(); t != nil {
+		return t.Time
+	}
+	return time.Time{}
+}
+
+func (cc *CounterController) reportIngressHosts(w io.Writer) {
+	// hosts shared by ingresses of several namespaces are only counted once,
+	// which the per-namespace sums of the hosts column can't tell.
+	hosts := map[string]bool{}
+	ingresses := 0
+	for id, informer := range cc.informers {
+		if kind, _ := splitID(id); kind != "Ingress" {
+			continue
+		}
+		for _, obj := range informer.GetStore().List() {
+			o, ok := obj.(*unstructured.Unstructured)
+			if !ok || !cc.match(o) {
+				continue
+			}
+			ingresses++
+			for host := range ingressHosts(o) {
+				hosts[host] = true
+			}
+		}
+	}
+	if ingresses > 0 {
+		fmt.Fprintf(w, "[Ingress] %d distinct hostnames across %d ingresses\n", len(hosts), ingresses)
+	}
+}
+
+func (cc *CounterController) Classify(classifiers ...Classifier) {
+	cc.classifiers = append(cc.classifiers, classifiers...)
+}
+
+func (cc *CounterController) Filter(filters ...Filter) {
+	cc.filters = append(cc.filters, filters...)
+}
+
+func (cc *CounterController) match(obj interface{}) bool {
+	o, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	for _, filter := range cc.filters {
+		if !filter.Match(o) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cc *CounterController) analyze(obj *unstructured.Unstructured) Sample {
+	sample := Sample{Created: obj.GetCreationTimestamp().Time}
+	for _, classifier := range cc.classifiers {
+		for k, v := range classifier.Classify(obj) {
+			if sample.Breakdown == nil {
+				sample.Breakdown = map[string]string{}
+			}
+			sample.Breakdown[k] = v
+		}
+	}
+
+	for _, analyzer := range cc.analyzers {
+		for k, v := range analyzer.Analyze(obj) {
+			if sample.Metrics == nil {
+				sample.Metrics = map[string]int{}
+			}
+			sample.Metrics[k] += v
+		}
+		if deadlineAnalyzer, ok := analyzer.(DeadlineAnalyzer); ok {
+			for k, t := range deadlineAnalyzer.Deadlines(obj) {
+				if sample.Deadlines == nil {
+					sample.Deadlines = map[string]time.Time{}
+				}
+				sample.Deadlines[k] = t
+			}
+		}
+	}
+	return sample
+}
+
+func (cc *CounterController) columns(records []Record) ([]string, []string) {
+	seen := map[string]bool{}
+	for _, record := range records {
+		for k := range record.Breakdown {
+			seen[k] = true
+		}
+		for k := range record.Metrics {
+			seen[k] = true
+		}
+	}
+
+	var breakdownColumns, metricColumns []string
+	for _, classifier := range cc.classifiers {
+		for _, column := range classifier.Columns() {
+			if seen[column] {
+				breakdownColumns = append(breakdownColumns, column)
+				delete(seen, column)
+			}
+		}
+	}
+	for _, analyzer := range cc.analyzers {
+		for _, column := range analyzer.Columns() {
+			if seen[column] {
+				metricColumns = append(metricColumns, column)
+				delete(seen, column)
+			}
+		}
+	}
+	return breakdownColumns, metricColumns
+}
+
+// averageColumns are the averages of the analyzers whose metric is shown.
+func (cc *CounterController) averageColumns(metricColumns []string) []Average {
+	shown := map[string]bool{}
+	for _, column := range metricColumns {
+		shown[column] = true
+	}
+
+	var averages []Average
+	for _, analyzer := range cc.analyzers {
+		averager, ok := analyzer.(Averager)
+		if !ok {
+			continue
+		}
+		for _, average := range averager.Averages() {
+			if shown[average.Metric] {
+				averages = append(averages, average)
+			}
+		}
+	}
+	return averages
+}
+
+func averagesOf(record Record, averages []Average) map[string]float64 {
+	if record.Count <= 0 || len(averages) == 0 {
+		return nil
+	}
+
+	ret := map[string]float64{}
+	for _, average := range averages {
+		ret[average.Column] = math.Round(float64(record.Metrics[average.Metric])/float64(record.Count)*100) / 100
+	}
+	return ret
+}
+
+// withAverages sets the averages of the records for machine-readable output.
+func (cc *CounterController) withAverages(records []Record) []Record {
+	_, metricColumns := cc.columns(records)
+	averageColumns := cc.averageColumns(metricColumns)
+	if len(averageColumns) == 0 {
+		return records
+	}
+
+	for i := range records {
+		records[i].Averages = averagesOf(records[i], averageColumns)
+	}
+	return records
+}
+
+func averageCells(record Record, averageColumns []Average) []string {
+	averages := averagesOf(record, averageColumns)
+	cells := make([]string, 0, len(averageColumns))
+	for _, average := range averageColumns {
+		value, ok := averages[average.Column]
+		if !ok {
+			cells = append(cells, "")
+			continue
+		}
+		cells = append(cells, strconv.FormatFloat(value, 'f', 2, 64))
+	}
+	return cells
+}
+
+// argsKinds merges kinds given as several arguments, comma separated or not, e.g. pods ds,deploy.
+func argsKinds(args []string) string {
+	var kinds []string
+	seen := map[string]bool{}
+	for _, arg := range args {
+		for _, part := range strings.Split(arg, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" && !seen[part] {
+				seen[part] = true
+				kinds = append(kinds, part)
+			}
+		}
+	}
+	return strings.Join(kinds, ",")
+}
+
+func (cc *CounterController) sanitizeKinds(s string) []string {
+	var kinds []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			kinds = append(kinds, part)
+		}
+	}
+	return kinds
+}
+
+func (cc *CounterController) list(s string) (*IDMap, error) {
+	if cc.bookmarks != "" {
+		return cc.incremental(s)
+	}
+	if cc.memoryLimit > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		cc.pressure = make(chan struct{})
+		go cc.watchMemory(done)
+	}
+	idMap, err := cc.sync(s, nil)
+	cc.cancel()
+	return idMap, err
+}
+
+func (cc *CounterController) sync(s string, extraHandler func(id string) cache.ResourceEventHandler) (*IDMap, error) {
+	kinds := cc.sanitizeKinds(s)
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("invalid input kind name: '%s'", s)
+	}
+
+	discoveryStarted := time.Now()
+	apiResources, err := cc.getApiResources()
+	if err != nil {
+		return nil, err
+	}
+	cc.timing.ObserveDiscovery(time.Since(discoveryStarted))
+	logger.Info("discovery finished", "resources", len(apiResources), "duration", time.Since(discoveryStarted).String())
+
+	idMap := NewIDMap()
+	informers := map[string]cache.SharedIndexInformer{}
+	resources := map[string]APIResourceGV{}
+	for _, kind := range kinds {
+		ars, ok := apiResources[kind]
+		if !ok {
+			logger.Info("kind not resolved", "kind", kind)
+			// a kind added after the discovery was cached is missing from it.
+			if !cc.discoveryClient.Fresh() {
+				cc.warn("StaleDiscovery", "%s isn't in the discovery cache, it may be stale, use --no-cache to rediscover", kind)
+				continue
+			}
+			cc.warn("KindNotFound", "%s doesn't match any resource which can be listed and watched", kind)
+			continue
+		}
+		for _, ar := range ars {
+			gvr := schema.GroupVersionResource{
+				Group:    ar.resource.Group,
+				Version:  ar.resource.Version,
+				Resource: ar.resource.Name,
+			}
+			if informer, ok := cc.protobufInformer(ar); ok {
+				informers[ar.ID()] = informer
+			} else {
+				informers[ar.ID()] = cc.factory.ForResource(gvr).Informer()
+			}
+			resources[ar.ID()] = ar
+			idMap.AddID(ar.ID(), ar.resource.Namespaced)
+			logger.Info("kind resolved", "kind", kind, "gvr", gvr.String())
+		}
+	}
+
+	if len(informers) == 0 {
+		return nil, errors.New("no available informers found")
+	}
+	cc.informers = informers
+
+	deniedCh := map[string]chan struct{}{}
+	fallbackCh := map[string]chan struct{}{}
+	for id, informer := range informers {
+		cloned := id
+		denied := make(chan struct{})
+		deniedCh[id] = denied
+		fallback := make(chan struct{})
+		fallbackCh[id] = fallback
+		synced := informer.HasSynced
+		var once, fallbackOnce sync.Once
+		var failures int32
+		informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+			klog.V(2).Infof("watch %s failed: %v", cloned, err)
+			logger.Error("watch failed", err, "id", cloned)
+			// retrying won't grant the permissions, give up on the kind right away.
+			if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+				once.Do(func() { close(denied) })
+				return
+			}
+			// an informer keeps failing to sync on a flaky aggregated API or a
+			// broken conversion webhook, a direct paged list may still go through.
+			if !synced() && atomic.AddInt32(&failures, 1) >= syncFailuresBeforeFallback {
+				fallbackOnce.Do(func() { close(fallback) })
+			}
+		})
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				o, ok := obj.(*unstructured.Unstructured)
+				if !ok || !cc.match(o) {
+					return
+				}
+				idMap.Add(cloned, o.GetNamespace(), cc.analyze(o))
+				cc.timing.ObserveObject(cloned)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if len(cc.classifiers) == 0 && len(cc.analyzers) == 0 && len(cc.filters) == 0 {
+					return
+				}
+				old, ok := oldObj.(*unstructured.Unstructured)
+				if !ok {
+					return
+				}
+				o, ok := newObj.(*unstructured.Unstructured)
+				if !ok {
+					return
+				}
+				if cc.match(old) {
+					idMap.Del(cloned, old.GetNamespace(), cc.analyze(old))
+				}
+				if cc.match(o) {
+					idMap.Add(cloned, o.GetNamespace(), cc.analyze(o))
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				o, ok := obj.(*unstructured.Unstructured)
+				if !ok || !cc.match(o) {
+					return
+				}
+				idMap.Del(cloned, o.GetNamespace(), cc.analyze(o))
+			},
+		})
+		if extraHandler != nil {
+			informer.AddEventHandler(cache.FilteringResourceEventHandler{
+				FilterFunc: cc.match,
+				Handler:    extraHandler(cloned),
+			})
+		}
+	}
+
+	var sem chan struct{}
+	if cc.maxConcurrent > 0 {
+		sem = make(chan struct{}, cc.maxConcurrent)
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var failed, denied []string
+	for kind, informer := range informers {
+		wg.Add(1)
+		go func(kind string, informer cache.SharedIndexInformer) {
+			defer wg.Done()
+			fail := func() {
+				lock.Lock()
+				defer lock.Unlock()
+				select {
+				case <-deniedCh[kind]:
+					denied = append(denied, kind)
+				default:
+					failed = append(failed, kind)
+				}
+				logger.Info("cache sync failed", "id", kind)
+			}
+			paged := func(approximate bool) {
+				syncStarted := time.Now()
+				idMap.Drop(kind)
+				if err := cc.countPages(kind, resources[kind], idMap); err != nil {
+					logger.Error("paged list failed", err, "id", kind)
+					fail()
+					return
+				}
+				if approximate {
+					idMap.Approximate(kind)
+					cc.warn("Approximate", "%s was counted with a paged list after its informer failed to sync, the count may be off by the changes during the list", kind)
+				}
+				cc.timing.ObserveSync(kind, time.Since(syncStarted))
+				logger.Info("paged list finished", "id", kind, "duration", time.Since(syncStarted).String())
+			}
+
+			skip := func() {
+				idMap.Drop(kind)
+				logger.Info("cache sync timed out", "id", kind, "timeout", cc.perKindTimeout.String())
+				fmt.Fprintf(os.Stderr, "[Warning] %s didn't sync within --per-kind-timeout %s, it is not counted\n", kind, cc.perKindTimeout)
+				cc.warn("Timeout", "%s didn't sync within --per-kind-timeout %s and was skipped", kind, cc.perKindTimeout)
+			}
+
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-cc.ctx.Done():
+					fail()
+					return
+				case <-cc.pressure:
+					paged(false)
+					return
+				}
+			}
+
+			// the timeout starts once the kind got its turn with --max-concurrent.
+			var timedOut chan struct{}
+			if cc.perKindTimeout > 0 {
+				timedOut = make(chan struct{})
+				timer := time.AfterFunc(cc.perKindTimeout, func() { close(timedOut) })
+				defer timer.Stop()
+			}
+
+			stopCh := make(chan struct{})
+			go func() {
+				select {
+				case <-cc.ctx.Done():
+				case <-deniedCh[kind]:
+				case <-cc.pressure:
+				case <-fallbackCh[kind]:
+				case <-timedOut:
+				}
+				close(stopCh)
+			}()
+
+			syncStarted := time.Now()
+			go informer.Run(stopCh)
+			if !cache.WaitForNamedCacheSync(kind, stopCh, informer.HasSynced) {
+				select {
+				case <-cc.ctx.Done():
+				case <-deniedCh[kind]:
+				case <-cc.pressure:
+					paged(false)
+					return
+				case <-fallbackCh[kind]:
+					paged(true)
+					return
+				case <-timedOut:
+					skip()
+					return
+				}
+				fail()
+				return
+			}
+			cc.timing.ObserveSync(kind, time.Since(syncStarted))
+			logger.Info("cache synced", "id", kind, "objects", len(informer.GetStore().ListKeys()), "duration", time.Since(syncStarted).String())
+		}(kind, informer)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 || len(denied) > 0 {
+		if atomic.LoadInt32(&cc.interrupted) == 1 {
+			return idMap, errInterrupted
+		}
+		if len(denied) > 0 {
+			sort.Strings(denied)
+			return nil, fmt.Errorf("%w to list %s", errAccessDenied, strings.Join(denied, ", "))
+		}
+		sort.Strings(failed)
+		return nil, fmt.Errorf("failed to sync %s cache", strings.Join(failed, ", "))
+	}
+	return idMap, nil
+}
+
+type APIResourceGV struct {
+	resource     v1.APIResource
+	groupVersion string
+}
+
+func (agv APIResourceGV) ID() string {
+	return agv.resource.Kind + "+" + agv.groupVersion
+}
+
+func (agv APIResourceGV) listable() bool {
+	var list, watch bool
+	for _, verb := range agv.resource.Verbs {
+		switch verb {
+		case "list":
+			list = true
+		case "watch":
+			watch = true
+		}
+	}
+	return list && watch
+}
+
+func (agv APIResourceGV) keys() map[string][]string {
+	r := agv.resource
+	keys := map[string][]string{}
+	add := func(key, source string) {
+		if key != "" {
+			keys[key] = append(keys[key], source)
+		}
+	}
+	add(r.Name, "name")
+	add(strings.ToLower(r.Kind), "kind")
+	add(fmt.Sprintf("%s.%s", r.Name, r.Group), "name.group")
+	for _, shortName := range r.ShortNames {
+		add(shortName, "shortName")
+	}
+	add(r.SingularName, "singularName")
+	return keys
+}
+
+func (cc *CounterController) serverResources() ([]APIResourceGV, error) {
+	resources, err := cc.discoveryClient.ServerPreferredResources()
+	if err := cc.discoveryFailed(err, len(resources)); err != nil {
+		return nil, err
+	}
+
+	var agvs []APIResourceGV
+	for _, resource := range resources {
+		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
+		if err != nil {
 			return nil, err
 		}
 
@@ -307,82 +1541,580 @@ func (cc *CounterController) getApiResources() (map[string][]APIResourceGV, erro
 			cloned := r
 			cloned.Group = gv.Group
 			cloned.Version = gv.Version
-			agv := APIResourceGV{resource: cloned, groupVersion: resource.GroupVersion}
+			agvs = append(agvs, APIResourceGV{resource: cloned, groupVersion: resource.GroupVersion})
+		}
+	}
+	return agvs, nil
+}
 
-			keys := []string{r.Name, strings.ToLower(r.Kind), fmt.Sprintf("%s.%s", r.Name, gv.Group)}
-			for _, key := range keys {
-				rm[key] = append(rm[key], agv)
-			}
+func (cc *CounterController) getApiResources() (map[string][]APIResourceGV, error) {
+	agvs, err := cc.serverResources()
+	if err != nil {
+		return nil, err
+	}
 
-			for _, shortName := range r.ShortNames {
-				rm[shortName] = append(rm[shortName], agv)
-			}
-			if r.SingularName != "" {
-				rm[r.SingularName] = append(rm[r.SingularName], agv)
-			}
+	rm := make(map[string][]APIResourceGV)
+	for _, agv := range agvs {
+		// informers can never sync resources which can't be listed and watched.
+		if !agv.listable() {
+			klog.V(1).Infof("skipping %s.%s, verbs %v lack list or watch", agv.resource.Name, agv.groupVersion, agv.resource.Verbs)
+			logger.Info("resource skipped", "resource", agv.resource.Name, "groupVersion", agv.groupVersion, "verbs", strings.Join(agv.resource.Verbs, ","))
+			continue
+		}
+		for key := range agv.keys() {
+			rm[key] = append(rm[key], agv)
 		}
 	}
-
 	return rm, nil
 }
 
-func (cc *CounterController) tableRender(records []Record) {
-	headers := []string{"Namespace", "GroupVersion", "Kind", "Count"}
-	table := tablewriter.NewWriter(os.Stdout)
+func (cc *CounterController) tableRender(w io.Writer, records []Record, wide bool) {
+	withCluster := false
+	for _, record := range records {
+		if record.Cluster != "" {
+			withCluster = true
+			break
+		}
+	}
+
+	breakdownColumns, metricColumns := cc.columns(records)
+	headers := []string{"Namespace", "GroupVersion", "Kind"}
+	if withCluster {
+		headers = append([]string{"Cluster"}, headers...)
+	}
+	for _, column := range breakdownColumns {
+		headers = append(headers, strings.ToUpper(column[:1])+column[1:])
+	}
+	headers = append(headers, "Count")
+	withChange := false
+	for _, record := range records {
+		if record.Change != nil {
+			withChange = true
+			break
+		}
+	}
+	if withChange {
+		headers = append(headers, "Change")
+	}
+	for _, column := range metricColumns {
+		headers = append(headers, strings.ToUpper(column[:1])+column[1:])
+	}
+	averageColumns := cc.averageColumns(metricColumns)
+	for _, average := range averageColumns {
+		headers = append(headers, strings.ToUpper(average.Column[:1])+average.Column[1:])
+	}
+
+	withNamespaces := false
+	for _, record := range records {
+		if record.Namespaces > 0 {
+			withNamespaces = true
+			break
+		}
+	}
+	if wide {
+		headers = append(headers, "Scope", "Oldest", "Newest")
+		if withNamespaces {
+			headers = append(headers, "Namespaces")
+		}
+		headers = append(headers, "Sync", "Objects")
+	}
+
+	table := cc.newTable(w)
 	table.SetHeader(headers)
 	table.SetAutoFormatHeaders(false)
 	table.SetAutoMergeCells(true)
 	table.SetRowLine(true)
 
+	// counts over their thresholds are colored when the table goes to a terminal.
+	highlight := cc.outputFile == "" && term.IsTerminal(int(os.Stdout.Fd()))
+
 	for _, record := range records {
-		table.Append([]string{record.Namespace, record.GroupVersion, record.Kind, strconv.Itoa(record.Count)})
+		row := []string{record.Namespace, record.GroupVersion, record.Kind}
+		if withCluster {
+			row = append([]string{record.Cluster}, row...)
+		}
+		for _, column := range breakdownColumns {
+			row = append(row, record.Breakdown[column])
+		}
+		count := humanizeCount(record.Count, cc.humanize)
+		if record.Approximate {
+			count = "~" + count
+		}
+		row = append(row, count)
+		countColumn := len(row) - 1
+		if withChange {
+			row = append(row, record.Change.String())
+		}
+		for _, column := range metricColumns {
+			row = append(row, humanizeCount(record.Metrics[column], cc.humanize))
+		}
+		row = append(row, averageCells(record, averageColumns)...)
+		if wide {
+			row = append(row, record.Scope, age(record.Oldest), age(record.Newest))
+			if withNamespaces {
+				row = append(row, strconv.Itoa(record.Namespaces))
+			}
+			row = append(row, record.SyncDuration.Round(time.Millisecond).String(), humanizeCount(record.Processed, cc.humanize))
+		}
+		if severity := cc.breachOf(record); highlight && severity != "" {
+			colors := make([]tablewriter.Colors, len(row))
+			colors[countColumn] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgYellowColor}
+			if severity == "critical" {
+				colors[countColumn] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor}
+			}
+			table.Rich(row, colors)
+			continue
+		}
+		table.Append(row)
+	}
+
+	if cc.subtotals {
+		for _, subtotal := range namespaceSubtotals(records) {
+			row := []string{subtotal.Namespace, "", "Subtotal"}
+			if withCluster {
+				row = append([]string{subtotal.Cluster}, row...)
+			}
+			for range breakdownColumns {
+				row = append(row, "")
+			}
+			row = append(row, humanizeCount(subtotal.Count, cc.humanize))
+			if withChange {
+				row = append(row, "")
+			}
+			for _, column := range metricColumns {
+				row = append(row, humanizeCount(subtotal.Metrics[column], cc.humanize))
+			}
+			row = append(row, averageCells(subtotal, averageColumns)...)
+			if wide {
+				row = append(row, "", age(subtotal.Oldest), age(subtotal.Newest))
+				if withNamespaces {
+					row = append(row, "")
+				}
+				row = append(row, "", "")
+			}
+			table.Append(row)
+		}
 	}
 	table.Render()
 }
 
-func (cc *CounterController) jsonRender(records []Record) {
+func namespaceSubtotals(records []Record) []Record {
+	var keys []string
+	subtotals := map[string]*Record{}
+	for _, record := range records {
+		if record.Namespace == "" {
+			continue
+		}
+		key := record.Cluster + "/" + record.Namespace
+		subtotal, ok := subtotals[key]
+		if !ok {
+			subtotal = &Record{Cluster: record.Cluster, Namespace: record.Namespace, Metrics: map[string]int{}}
+			subtotals[key] = subtotal
+			keys = append(keys, key)
+		}
+		subtotal.Count += record.Count
+		for k, v := range record.Metrics {
+			subtotal.Metrics[k] += v
+		}
+		subtotal.observeAge(record.Oldest, record.Newest)
+	}
+
+	sort.Strings(keys)
+	ret := make([]Record, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, *subtotals[key])
+	}
+	return ret
+}
+
+func (cc *CounterController) jsonRender(w io.Writer, records interface{}) {
 	b, err := json.MarshalIndent(records, "", " ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[Oh...] Failed to marshal JSON data, error: %v", err)
-		os.Exit(1)
+		exit(exitFailure)
 	}
-	fmt.Println(string(b))
+	fmt.Fprintln(w, string(b))
 }
 
-func (cc *CounterController) yamlRender(records []Record) {
+func (cc *CounterController) yamlRender(w io.Writer, records interface{}) {
 	b, err := yaml.Marshal(records)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[Oh...] Failed to marshal YAML data, error: %v", err)
-		os.Exit(1)
+		exit(exitFailure)
 	}
-	fmt.Println(string(b))
+	fmt.Fprintln(w, string(b))
 }
 
-func (cc *CounterController) Render(kinds, order, output string, allNamespace bool) {
+func (cc *CounterController) Records(kinds, order string, allNamespace bool) ([]Record, error) {
+	// namespaces only show up per namespace, and are listed before the
+	// context is gone with the sync.
+	var namespaces []string
+	if cc.emptyNamespaces && !allNamespace && cc.namespace == "" {
+		_, items, err := cc.listResource("namespaces", "")
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if cc.selectedNamespaces != nil && !cc.selectedNamespaces[item.GetName()] {
+				continue
+			}
+			namespaces = append(namespaces, item.GetName())
+		}
+	}
+
 	idMap, err := cc.list(kinds)
+	cc.summarize()
+	if err != nil && !errors.Is(err, errInterrupted) {
+		return nil, err
+	}
+	if err == nil {
+		idMap.AddEmptyNamespaces(namespaces)
+	}
+	// limits of a namespace apply with -A as well.
+	if err == nil && cc.thresholds != nil {
+		cc.breaches = cc.thresholds.breaches(idMap.GetRecords(order, false))
+	}
+	records := idMap.GetRecords(order, allNamespace)
+	for i := range records {
+		id := records[i].Kind + "+" + records[i].GroupVersion
+		records[i].SyncDuration = cc.timing.SyncDuration(id)
+		records[i].Processed = cc.timing.Objects(id)
+	}
+	return records, nil
+}
+
+func (cc *CounterController) Render(kinds, order, output string, allNamespace bool) {
+	records, err := cc.Records(kinds, order, allNamespace)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
-		os.Exit(1)
+		logger.Error("list resources failed", err)
+		cc.fail(output, "Failed to list resources", err)
+	}
+	cc.escalateWarnings(output)
+	cc.output(records, output)
+	cc.enforceThresholds()
+}
+
+func (cc *CounterController) refuseTerminal(format string) {
+	if cc.outputFile == "" && term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprintf(os.Stderr, "[Oh...] Refusing to write %s data to a terminal, use --output-file!\n", format)
+		exit(exitFailure)
+	}
+}
+
+func (cc *CounterController) recordEnvelope(records []Record) Envelope {
+	envelope := newEnvelope("RecordList", cc.withAverages(records))
+	envelope.Errors = cc.errors
+	envelope.Warnings = cc.warnings
+	if atomic.LoadInt32(&cc.interrupted) == 1 {
+		envelope.Errors = append(envelope.Errors, newOutputError("", errInterrupted))
+	}
+	return envelope
+}
+
+func (cc *CounterController) output(records []Record, output string) {
+	// the counts are posted whatever they are printed as, empty ones too.
+	if cc.postTarget != nil {
+		cc.postRecords(records)
+	}
+	if cc.textfile != "" {
+		cc.writeTextfile(records)
+		if len(records) <= 0 {
+			cc.empty(output)
+			return
+		}
+		if cc.partial() {
+			exit(exitInterrupted)
+		}
+		return
 	}
 
-	records := idMap.GetRecords(order, allNamespace)
 	if len(records) <= 0 {
-		fmt.Fprintln(os.Stdout, "[Oh...] No Resources found!")
-		os.Exit(1)
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+		records = []Record{}
 	}
 
+	envelope := cc.recordEnvelope(records)
+	var buf bytes.Buffer
 	switch output {
 	case "json", "j":
-		cc.jsonRender(records)
+		cc.jsonRender(&buf, envelope)
 	case "yaml", "y":
-		cc.yamlRender(records)
+		cc.yamlRender(&buf, envelope)
+	case "wide", "w":
+		cc.tableRender(&buf, records, true)
+	case "xlsx":
+		cc.refuseTerminal("XLSX")
+		cc.xlsxRender(&buf, records)
+	case "parquet":
+		cc.refuseTerminal("Parquet")
+		cc.parquetRender(&buf, records)
+	case "sql":
+		cc.sqlRender(&buf, records)
+	case "grafana":
+		cc.grafanaRender(&buf, records)
+	case "grafana-dashboard":
+		cc.grafanaDashboardRender(&buf, records)
 	default:
-		cc.tableRender(records)
+		cc.tableRender(&buf, records, false)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
+
+func newController(cmd *cobra.Command, configFlags *genericclioptions.ConfigFlags) (*CounterController, error) {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	return newNamespacedController(cmd, configFlags, namespace)
+}
+
+func newNamespacedController(cmd *cobra.Command, configFlags *genericclioptions.ConfigFlags, namespace string) (*CounterController, error) {
+	maxRetries, _ := cmd.Flags().GetInt("max-retries")
+	cacheTTL, _ := cmd.Flags().GetDuration("cache-ttl")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	noProtobuf, _ := cmd.Flags().GetBool("no-protobuf")
+	maxConcurrent, _ := cmd.Flags().GetInt("max-concurrent")
+	ctr, err := NewCounterController(ControllerOptions{
+		ConfigFlags:   configFlags,
+		Namespace:     namespace,
+		MaxRetries:    maxRetries,
+		CacheTTL:      cacheTTL,
+		NoCache:       noCache,
+		NoProtobuf:    noProtobuf,
+		MaxConcurrent: maxConcurrent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctr.HandleSignals()
+	ctr.configure(cmd)
+	return ctr, nil
+}
+
+func (cc *CounterController) configure(cmd *cobra.Command) {
+	if managedFields, _ := cmd.Flags().GetBool("managed-fields"); managedFields {
+		maxEntries, _ := cmd.Flags().GetInt("managed-fields-max-entries")
+		maxBytes, _ := cmd.Flags().GetInt("managed-fields-max-bytes")
+		cc.Use(ManagedFieldsAnalyzer{MaxEntries: maxEntries, MaxBytes: maxBytes})
+	}
+
+	showTiming, _ := cmd.Flags().GetBool("show-timing")
+	cc.ShowTiming(showTiming)
+	humanize, _ := cmd.Flags().GetString("humanize")
+	cc.Humanize(humanize)
+	noPager, _ := cmd.Flags().GetBool("no-pager")
+	cc.DisablePager(noPager)
+	outputFile, _ := cmd.Flags().GetString("output-file")
+	cc.OutputFile(outputFile)
+	sqlTable, _ := cmd.Flags().GetString("sql-table")
+	cc.SQLTable(sqlTable)
+	noFailOnEmpty, _ := cmd.Flags().GetBool("no-fail-on-empty")
+	cc.NoFailOnEmpty(noFailOnEmpty)
+	textfile, _ := cmd.Flags().GetString("textfile")
+	cc.Textfile(textfile)
+	if postURL, _ := cmd.Flags().GetString("post-url"); postURL != "" {
+		token, _ := cmd.Flags().GetString("post-token")
+		headers, _ := cmd.Flags().GetStringArray("post-header")
+		target, err := NewPostTarget(postURL, token, headers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Invalid --post-url target, error: %v", err)
+			exit(exitFailure)
+		}
+		cc.postTarget = target
+	}
+	subtotals, _ := cmd.Flags().GetBool("subtotals")
+	cc.Subtotals(subtotals)
+	tableStyle, _ := cmd.Flags().GetString("table-style")
+	if !tableStyles[tableStyle] {
+		fmt.Fprintf(os.Stderr, "[Oh...] Unknown table style '%s', use grid, rounded, borderless or plain!\n", tableStyle)
+		exit(exitFailure)
+	}
+	cc.tableStyle = tableStyle
+	noMergeCells, _ := cmd.Flags().GetBool("no-merge-cells")
+	cc.noMergeCells = noMergeCells
+	compact, _ := cmd.Flags().GetBool("compact")
+	cc.compact = compact
+	maxColWidth, _ := cmd.Flags().GetInt("max-col-width")
+	if maxColWidth < 0 || maxColWidth == 1 {
+		fmt.Fprintf(os.Stderr, "[Oh...] Invalid max column width %d, use 0 or at least 2!\n", maxColWidth)
+		exit(exitFailure)
+	}
+	cc.maxColWidth = maxColWidth
+	strictDiscovery, _ := cmd.Flags().GetBool("strict-discovery")
+	cc.strictDiscovery = strictDiscovery
+	emptyNamespaces, _ := cmd.Flags().GetBool("show-empty-namespaces")
+	cc.emptyNamespaces = emptyNamespaces
+	strict, _ := cmd.Flags().GetBool("strict")
+	cc.strict = strict
+	perKindTimeout, _ := cmd.Flags().GetDuration("per-kind-timeout")
+	cc.perKindTimeout = perKindTimeout
+	if path, _ := cmd.Flags().GetString("thresholds"); path != "" {
+		thresholds, err := loadThresholds(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to load thresholds %s, error: %v", path, err)
+			exit(exitFailure)
+		}
+		cc.thresholds = thresholds
+	}
+	if memoryLimit, _ := cmd.Flags().GetString("memory-limit"); memoryLimit != "" {
+		q, err := resource.ParseQuantity(memoryLimit)
+		if err != nil || q.Sign() <= 0 {
+			fmt.Fprintf(os.Stderr, "[Oh...] Invalid memory limit '%s', use a quantity like 500Mi!\n", memoryLimit)
+			exit(exitFailure)
+		}
+		cc.memoryLimit = uint64(q.Value())
+	}
+	// bookmarks hold the objects of every kind, they are shared by the runs
+	// counting different kinds with the same flags.
+	if incremental, _ := cmd.Flags().GetBool("incremental"); incremental && cc.dynamicClient != nil {
+		path, err := cachePath(cmd, "bookmarks", "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to load kubeconfig, error: %v", err)
+			exit(exitFailure)
+		}
+		cc.bookmarks = path
+	}
+	if breakdown, _ := cmd.Flags().GetBool("breakdown"); breakdown {
+		cc.Classify(breakdownClassifiers...)
+	}
+	if nodeConditions, _ := cmd.Flags().GetBool("node-conditions"); nodeConditions {
+		cc.Classify(NodeConditionClassifier{})
+	}
+	if staleJobsAfter, _ := cmd.Flags().GetDuration("stale-jobs-after"); staleJobsAfter > 0 {
+		cc.Use(StaleJobsAnalyzer{After: staleJobsAfter})
+	}
+	restarts, _ := cmd.Flags().GetBool("restarts")
+	restartsThreshold, _ := cmd.Flags().GetInt("restarts-threshold")
+	if restarts || restartsThreshold > 0 {
+		cc.Use(RestartsAnalyzer{Threshold: restartsThreshold})
+	}
+	if labels, _ := cmd.Flags().GetStringSlice("require-labels"); len(labels) > 0 {
+		cc.Use(RequiredLabelsAnalyzer{Labels: labels})
+	}
+	if dataSize, _ := cmd.Flags().GetBool("data-size"); dataSize {
+		cc.Classify(DataSizeClassifier{})
+	}
+	ingressRules, _ := cmd.Flags().GetBool("ingress-rules")
+	cc.IngressRules(ingressRules)
+	if containers, _ := cmd.Flags().GetBool("containers"); containers {
+		cc.Use(ContainersAnalyzer{})
+	}
+	if replicas, _ := cmd.Flags().GetBool("replicas"); replicas {
+		cc.Use(ReplicasAnalyzer{})
+	}
+	if name, _ := cmd.Flags().GetString("name"); name != "" {
+		filter, err := NewNameFilter(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Invalid name regex '%s', error: %v", name, err)
+			exit(exitFailure)
+		}
+		cc.Filter(filter)
+	}
+	if key, _ := cmd.Flags().GetString("missing-label"); key != "" {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "[Oh...] Invalid label key '%s', error: %s", key, strings.Join(errs, "; "))
+			exit(exitFailure)
+		}
+		cc.Filter(MissingLabelFilter{Key: key})
+	}
+	// offline controllers have no cluster to list the namespaces or look the owner up in.
+	if selector, _ := cmd.Flags().GetString("namespace-selector"); selector != "" && cc.dynamicClient != nil {
+		filter, err := cc.NewNamespaceFilter(selector)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to list namespaces matching '%s', error: %v", selector, err)
+			exit(exitCode(err))
+		}
+		if len(filter.Namespaces) == 0 {
+			fmt.Fprintf(os.Stderr, "[Warning] No namespace matches --namespace-selector %s, nothing is counted\n", selector)
+		}
+		cc.selectedNamespaces = filter.Namespaces
+		cc.Filter(filter)
+	}
+	if ownedBy, _ := cmd.Flags().GetString("owned-by"); ownedBy != "" && cc.dynamicClient != nil {
+		namespace, _ := cmd.Flags().GetString("namespace")
+		if namespace == "" {
+			namespace, _, _ = cf.ToRawKubeConfigLoader().Namespace()
+		}
+		filter, err := cc.NewOwnerFilter(ownedBy, namespace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to find owner %s, error: %v", ownedBy, err)
+			exit(exitCode(err))
+		}
+		cc.Filter(filter)
+	}
+	if byPlatform, _ := cmd.Flags().GetBool("by-platform"); byPlatform {
+		cc.Classify(NodeLabelClassifier{
+			Labels: []string{"kubernetes.io/os", "kubernetes.io/arch"},
+			Names:  []string{"os", "arch"},
+			Nodes:  cc.NodeLabels(),
+		})
+	}
+	if byZone, _ := cmd.Flags().GetBool("by-zone"); byZone {
+		cc.Classify(NodeLabelClassifier{
+			Labels: []string{"topology.kubernetes.io/region", "topology.kubernetes.io/zone"},
+			Names:  []string{"region", "zone"},
+			Nodes:  cc.NodeLabels(),
+		})
+	}
+	if labels, _ := cmd.Flags().GetStringSlice("by-node-label"); len(labels) > 0 {
+		cc.Classify(NodeLabelClassifier{
+			Labels: labels,
+			Names:  labels,
+			Nodes:  cc.NodeLabels(),
+		})
+	}
+	if labels, _ := cmd.Flags().GetStringSlice("group-by-label"); len(labels) > 0 {
+		cc.Classify(LabelClassifier{Labels: labels})
+	}
+	if label, _ := cmd.Flags().GetString("aggregate-by-namespace-label"); label != "" {
+		if errs := validation.IsQualifiedName(label); len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "[Oh...] Invalid label key '%s', error: %s", label, strings.Join(errs, "; "))
+			exit(exitFailure)
+		}
+		cc.Classify(NamespaceLabelClassifier{Label: label, Namespaces: cc.NamespaceMeta()})
+	}
+	if annotations, _ := cmd.Flags().GetStringSlice("attribute-by-annotation"); len(annotations) > 0 {
+		for _, annotation := range annotations {
+			if errs := validation.IsQualifiedName(annotation); len(errs) > 0 {
+				fmt.Fprintf(os.Stderr, "[Oh...] Invalid annotation key '%s', error: %s", annotation, strings.Join(errs, "; "))
+				exit(exitFailure)
+			}
+		}
+		cc.Classify(NamespaceAnnotationClassifier{Annotations: annotations, Namespaces: cc.NamespaceMeta()})
+	}
+}
+
+var exitHooks []func()
+
+func onExit(fn func()) {
+	exitHooks = append(exitHooks, fn)
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errInterrupted):
+		return exitInterrupted
+	case errors.Is(err, errAccessDenied), apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return exitAccessDenied
+	}
+	return exitFailure
+}
+
+func exit(code int) {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
 	}
+	exitHooks = nil
+	os.Exit(code)
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "[Oh...] Failed to exec command: %v", err)
+		exit(exitFailure)
 	}
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Logger struct {
+	lock   sync.Mutex
+	w      io.Writer
+	format string
+}
+
+var logger = &Logger{w: io.Discard}
+
+func setupLogger(format, file string) error {
+	switch format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("unsupported log format '%s', expected text or json", format)
+	}
+
+	switch {
+	case file != "":
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		onExit(func() { f.Close() })
+		logger = &Logger{w: f, format: format}
+	case format != "":
+		logger = &Logger{w: os.Stderr, format: format}
+	}
+	return nil
+}
+
+func (l *Logger) Info(msg string, kvs ...interface{}) {
+	l.log("info", msg, kvs...)
+}
+
+func (l *Logger) Error(msg string, err error, kvs ...interface{}) {
+	l.log("error", msg, append(kvs, "error", err.Error())...)
+}
+
+func (l *Logger) log(level, msg string, kvs ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.w == io.Discard {
+		return
+	}
+
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		fields[fmt.Sprint(kvs[i])] = kvs[i+1]
+	}
+
+	ts := time.Now().Format(time.RFC3339Nano)
+	if l.format == "json" {
+		fields["ts"] = ts
+		fields["level"] = level
+		fields["msg"] = msg
+		b, err := json.Marshal(fields)
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(l.w, string(b))
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %q", ts, level, msg)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %s=%v", k, fields[k])
+	}
+	fmt.Fprintln(l.w, sb.String())
+}
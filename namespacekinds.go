@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+type NamespaceKinds struct {
+	Namespace string   `json:"namespace" yaml:"namespace"`
+	Count     int      `json:"count" yaml:"count"`
+	Kinds     []string `json:"kinds" yaml:"kinds"`
+}
+
+func namespaceKinds(records []Record, order string) []NamespaceKinds {
+	// breakdowns split a kind into several records, a kind is counted once.
+	kinds := map[string]map[string]bool{}
+	for _, record := range records {
+		if record.Namespace == "" || record.Count == 0 {
+			continue
+		}
+		if kinds[record.Namespace] == nil {
+			kinds[record.Namespace] = map[string]bool{}
+		}
+		kinds[record.Namespace][record.Kind+"."+record.GroupVersion] = true
+	}
+
+	ret := make([]NamespaceKinds, 0, len(kinds))
+	for namespace, set := range kinds {
+		names := make([]string, 0, len(set))
+		for name := range set {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		ret = append(ret, NamespaceKinds{Namespace: namespace, Count: len(names), Kinds: names})
+	}
+
+	desc := isDesc(order)
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			if desc {
+				return ret[i].Count > ret[j].Count
+			}
+			return ret[i].Count < ret[j].Count
+		}
+		return ret[i].Namespace < ret[j].Namespace
+	})
+	return ret
+}
+
+func (cc *CounterController) namespaceKindsTableRender(w io.Writer, items []NamespaceKinds, wide bool) {
+	headers := []string{"Namespace", "Kinds"}
+	if wide {
+		headers = append(headers, "Names")
+	}
+	table := cc.newTable(w)
+	table.SetHeader(headers)
+	table.SetAutoFormatHeaders(false)
+	table.SetRowLine(true)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+
+	for _, item := range items {
+		row := []string{item.Namespace, humanizeCount(item.Count, cc.humanize)}
+		if wide {
+			row = append(row, strings.Join(item.Kinds, ", "))
+		}
+		table.Append(row)
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderNamespaceKinds(kinds, order, output string) {
+	records, err := cc.Records(kinds, order, false)
+	if err != nil {
+		logger.Error("list resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	items := namespaceKinds(records, order)
+	if len(items) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("NamespaceKindsList", items))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("NamespaceKindsList", items))
+	case "wide", "w":
+		cc.namespaceKindsTableRender(&buf, items, true)
+	default:
+		cc.namespaceKindsTableRender(&buf, items, false)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+type namespaceMeta struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+type NamespaceMeta struct {
+	cc   *CounterController
+	once sync.Once
+	lock sync.Mutex
+	m    map[string]namespaceMeta
+}
+
+func (cc *CounterController) NamespaceMeta() *NamespaceMeta {
+	if cc.namespaceMeta == nil {
+		cc.namespaceMeta = &NamespaceMeta{cc: cc, m: map[string]namespaceMeta{}}
+	}
+	return cc.namespaceMeta
+}
+
+func (nm *NamespaceMeta) Labels(name string) map[string]string {
+	return nm.get(name).labels
+}
+
+func (nm *NamespaceMeta) Annotations(name string) map[string]string {
+	return nm.get(name).annotations
+}
+
+func (nm *NamespaceMeta) get(name string) namespaceMeta {
+	// namespaces are listed once, the ones created later are fetched one by one.
+	nm.once.Do(func() {
+		list, err := nm.cc.dynamicClient.Resource(namespacesGVR).List(nm.cc.ctx, v1.ListOptions{})
+		if err != nil {
+			logger.Error("list namespaces failed", err)
+			return
+		}
+		nm.lock.Lock()
+		defer nm.lock.Unlock()
+		for _, namespace := range list.Items {
+			nm.m[namespace.GetName()] = namespaceMeta{labels: namespace.GetLabels(), annotations: namespace.GetAnnotations()}
+		}
+	})
+
+	nm.lock.Lock()
+	meta, ok := nm.m[name]
+	nm.lock.Unlock()
+	if ok {
+		return meta
+	}
+
+	namespace, err := nm.cc.dynamicClient.Resource(namespacesGVR).Get(nm.cc.ctx, name, v1.GetOptions{})
+	if err != nil {
+		logger.Error("get namespace failed", err, "name", name)
+		return namespaceMeta{}
+	}
+	nm.lock.Lock()
+	defer nm.lock.Unlock()
+	// the metadata first seen is kept, an object must land in the same bucket
+	// when it's deleted as when it was added.
+	if meta, ok := nm.m[name]; ok {
+		return meta
+	}
+	nm.m[name] = namespaceMeta{labels: namespace.GetLabels(), annotations: namespace.GetAnnotations()}
+	return nm.m[name]
+}
+
+type NamespaceLabelClassifier struct {
+	Label      string
+	Namespaces *NamespaceMeta
+}
+
+func (nlc NamespaceLabelClassifier) Columns() []string {
+	return []string{nlc.Label}
+}
+
+func (nlc NamespaceLabelClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	var labels map[string]string
+	switch {
+	case obj.GetNamespace() != "":
+		labels = nlc.Namespaces.Labels(obj.GetNamespace())
+	case groupOf(obj) == "" && obj.GetKind() == "Namespace":
+		labels = nlc.Namespaces.Labels(obj.GetName())
+		if labels == nil {
+			labels = obj.GetLabels()
+		}
+	default:
+		return nil
+	}
+
+	value, ok := labels[nlc.Label]
+	if !ok {
+		value = "<none>"
+	}
+	return map[string]string{nlc.Label: value}
+}
+
+const unattributed = "unattributed"
+
+type NamespaceAnnotationClassifier struct {
+	Annotations []string
+	Namespaces  *NamespaceMeta
+}
+
+func (nac NamespaceAnnotationClassifier) Columns() []string {
+	return nac.Annotations
+}
+
+func (nac NamespaceAnnotationClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	// every object lands in a bucket, cluster scoped ones are unattributed,
+	// so the attributed counts add up to the totals.
+	var annotations map[string]string
+	switch {
+	case obj.GetNamespace() != "":
+		annotations = nac.Namespaces.Annotations(obj.GetNamespace())
+	case groupOf(obj) == "" && obj.GetKind() == "Namespace":
+		annotations = nac.Namespaces.Annotations(obj.GetName())
+		if annotations == nil {
+			annotations = obj.GetAnnotations()
+		}
+	}
+
+	ret := make(map[string]string, len(nac.Annotations))
+	for _, annotation := range nac.Annotations {
+		value := strings.TrimSpace(annotations[annotation])
+		if value == "" {
+			value = unattributed
+		}
+		ret[annotation] = value
+	}
+	return ret
+}
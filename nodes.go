@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var nodesGVR = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+
+type NodeLabels struct {
+	cc   *CounterController
+	once sync.Once
+	lock sync.Mutex
+	m    map[string]map[string]string
+}
+
+func (cc *CounterController) NodeLabels() *NodeLabels {
+	if cc.nodeLabels == nil {
+		cc.nodeLabels = &NodeLabels{cc: cc, m: map[string]map[string]string{}}
+	}
+	return cc.nodeLabels
+}
+
+func (nl *NodeLabels) Get(name string) map[string]string {
+	// nodes are listed once, the ones joining later are fetched one by one.
+	nl.once.Do(func() {
+		list, err := nl.cc.dynamicClient.Resource(nodesGVR).List(nl.cc.ctx, v1.ListOptions{})
+		if err != nil {
+			logger.Error("list nodes failed", err)
+			return
+		}
+		nl.lock.Lock()
+		defer nl.lock.Unlock()
+		for _, node := range list.Items {
+			nl.m[node.GetName()] = node.GetLabels()
+		}
+	})
+
+	nl.lock.Lock()
+	labels, ok := nl.m[name]
+	nl.lock.Unlock()
+	if ok {
+		return labels
+	}
+
+	node, err := nl.cc.dynamicClient.Resource(nodesGVR).Get(nl.cc.ctx, name, v1.GetOptions{})
+	if err != nil {
+		logger.Error("get node failed", err, "name", name)
+		return nil
+	}
+	nl.lock.Lock()
+	defer nl.lock.Unlock()
+	// the labels first seen are kept, a pod must land in the same bucket when
+	// it's deleted as when it was added.
+	if labels, ok := nl.m[name]; ok {
+		return labels
+	}
+	nl.m[name] = node.GetLabels()
+	return nl.m[name]
+}
+
+type NodeLabelClassifier struct {
+	Labels []string
+	Names  []string
+	Nodes  *NodeLabels
+}
+
+func (nlc NodeLabelClassifier) Columns() []string {
+	return nlc.Names
+}
+
+func (nlc NodeLabelClassifier) Classify(obj *unstructured.Unstructured) map[string]string {
+	if groupOf(obj) != "" {
+		return nil
+	}
+
+	var labels map[string]string
+	switch obj.GetKind() {
+	case "Node":
+		labels = nlc.Nodes.Get(obj.GetName())
+		if labels == nil {
+			labels = obj.GetLabels()
+		}
+	case "Pod":
+		// pods are attributed to the node they are scheduled on.
+		nodeName, _, _ := unstructured.NestedString(obj.Object, "spec", "nodeName")
+		if nodeName != "" {
+			labels = nlc.Nodes.Get(nodeName)
+		}
+	default:
+		return nil
+	}
+
+	ret := make(map[string]string, len(nlc.Labels))
+	for i, label := range nlc.Labels {
+		value, ok := labels[label]
+		if !ok {
+			value = "<none>"
+		}
+		ret[nlc.Names[i]] = value
+	}
+	return ret
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+func pagerCommand() []string {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	return strings.Fields(pager)
+}
+
+func (cc *CounterController) write(b []byte) {
+	if cc.outputFile != "" {
+		if err := os.WriteFile(cc.outputFile, b, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to write output file, error: %v", err)
+			exit(exitFailure)
+		}
+		return
+	}
+
+	if cc.noPager || !cc.page(b) {
+		os.Stdout.Write(b)
+	}
+}
+
+func (cc *CounterController) page(b []byte) bool {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return false
+	}
+
+	_, height, err := term.GetSize(fd)
+	if err != nil || bytes.Count(b, []byte("\n")) < height {
+		return false
+	}
+
+	args := pagerCommand()
+	if len(args) == 0 || args[0] == "cat" {
+		return false
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	// the pager may exit non-zero once the output was shown, e.g. less quit with
+	// ctrl-c, only a pager which didn't start falls back to stdout.
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+	cmd.Wait()
+	return true
+}
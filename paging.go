@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	pageSize             = 500
+	memorySampleInterval = 250 * time.Millisecond
+)
+
+func (cc *CounterController) resourceInterface(ar APIResourceGV) dynamic.ResourceInterface {
+	gvr := schema.GroupVersionResource{Group: ar.resource.Group, Version: ar.resource.Version, Resource: ar.resource.Name}
+	if ar.resource.Namespaced {
+		return cc.dynamicClient.Resource(gvr).Namespace(cc.namespace)
+	}
+	return cc.dynamicClient.Resource(gvr)
+}
+
+func (cc *CounterController) listPages(ri dynamic.ResourceInterface, fn func(obj *unstructured.Unstructured)) (string, error) {
+	// only one page is held in memory at a time.
+	var resourceVersion string
+	opts := v1.ListOptions{Limit: pageSize}
+	for {
+		list, err := ri.List(cc.ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		// pages after the first one are served at the resourceVersion of the first.
+		if opts.Continue == "" {
+			resourceVersion = list.GetResourceVersion()
+		}
+		for i := range list.Items {
+			fn(&list.Items[i])
+		}
+		if list.GetContinue() == "" {
+			return resourceVersion, nil
+		}
+		opts.Continue = list.GetContinue()
+	}
+}
+
+func (cc *CounterController) countPages(id string, ar APIResourceGV, idMap *IDMap) error {
+	paged := NewIDMap()
+	_, err := cc.listPages(cc.resourceInterface(ar), func(obj *unstructured.Unstructured) {
+		if cc.match(obj) {
+			paged.Add(id, obj.GetNamespace(), cc.analyze(obj))
+			cc.timing.ObserveObject(id)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	idMap.Replace(id, paged)
+	return nil
+}
+
+func memoryInUse() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Sys - m.HeapReleased
+}
+
+func (cc *CounterController) watchMemory(done <-chan struct{}) {
+	ticker := time.NewTicker(memorySampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-cc.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if inUse := memoryInUse(); inUse > cc.memoryLimit {
+			logger.Info("memory limit exceeded", "inUse", inUse, "limit", cc.memoryLimit)
+			fmt.Fprintf(os.Stderr, "[Memory] %s in use is over --memory-limit %s, the kinds not synced yet are counted with paged lists\n",
+				resource.NewQuantity(int64(inUse), resource.BinarySI), resource.NewQuantity(int64(cc.memoryLimit), resource.BinarySI))
+			cc.warn("MemoryPressure", "%s in use is over --memory-limit, the kinds not synced yet were counted with paged lists", resource.NewQuantity(int64(inUse), resource.BinarySI))
+			close(cc.pressure)
+			return
+		}
+	}
+}
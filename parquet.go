@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	parquetMagic = "PAR1"
+
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+)
+
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+type thriftWriter struct {
+	buf    bytes.Buffer
+	lastID []int16
+}
+
+func (tw *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	tw.buf.Write(b[:n])
+}
+
+func (tw *thriftWriter) zigzag(v int64) {
+	tw.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (tw *thriftWriter) field(id int16, typ byte) {
+	last := tw.lastID[len(tw.lastID)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		tw.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		tw.buf.WriteByte(typ)
+		tw.zigzag(int64(id))
+	}
+	tw.lastID[len(tw.lastID)-1] = id
+}
+
+func (tw *thriftWriter) begin() {
+	tw.lastID = append(tw.lastID, 0)
+}
+
+func (tw *thriftWriter) end() {
+	tw.buf.WriteByte(0)
+	tw.lastID = tw.lastID[:len(tw.lastID)-1]
+}
+
+func (tw *thriftWriter) i32(id int16, v int32) {
+	tw.field(id, thriftI32)
+	tw.zigzag(int64(v))
+}
+
+func (tw *thriftWriter) i64(id int16, v int64) {
+	tw.field(id, thriftI64)
+	tw.zigzag(v)
+}
+
+func (tw *thriftWriter) str(id int16, v string) {
+	tw.field(id, thriftBinary)
+	tw.varint(uint64(len(v)))
+	tw.buf.WriteString(v)
+}
+
+func (tw *thriftWriter) list(id int16, typ byte, size int) {
+	tw.field(id, thriftList)
+	if size < 15 {
+		tw.buf.WriteByte(byte(size)<<4 | typ)
+		return
+	}
+	tw.buf.WriteByte(0xf0 | typ)
+	tw.varint(uint64(size))
+}
+
+func (tw *thriftWriter) structField(id int16) {
+	tw.field(id, thriftStruct)
+	tw.begin()
+}
+
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+	strings   []string
+	ints      []int64
+}
+
+func (pc *parquetColumn) plain() []byte {
+	var buf bytes.Buffer
+	var b [8]byte
+	if pc.typ == parquetInt64 {
+		for _, v := range pc.ints {
+			binary.LittleEndian.PutUint64(b[:], uint64(v))
+			buf.Write(b[:])
+		}
+		return buf.Bytes()
+	}
+	for _, v := range pc.strings {
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(v)))
+		buf.Write(b[:4])
+		buf.WriteString(v)
+	}
+	return buf.Bytes()
+}
+
+func writeParquet(w io.Writer, columns []*parquetColumn, rows int) error {
+	var body bytes.Buffer
+	body.WriteString(parquetMagic)
+
+	type chunk struct {
+		offset int64
+		size   int64
+	}
+	chunks := make([]chunk, len(columns))
+	for i, column := range columns {
+		data := column.plain()
+
+		header := &thriftWriter{}
+		header.begin()
+		header.i32(1, 0)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structField(5)
+		header.i32(1, int32(rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		chunks[i] = chunk{offset: int64(body.Len()), size: int64(header.buf.Len() + len(data))}
+		body.Write(header.buf.Bytes())
+		body.Write(data)
+	}
+
+	meta := &thriftWriter{}
+	meta.begin()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(columns)+1)
+	meta.begin()
+	meta.str(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.end()
+	for _, column := range columns {
+		meta.begin()
+		meta.i32(1, column.typ)
+		meta.i32(3, parquetRequired)
+		meta.str(4, column.name)
+		if column.converted >= 0 {
+			meta.i32(6, column.converted)
+		}
+		meta.end()
+	}
+	meta.i64(3, int64(rows))
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+	meta.list(4, thriftStruct, 1)
+	meta.begin()
+	meta.list(1, thriftStruct, len(columns))
+	for i, column := range columns {
+		meta.begin()
+		meta.i64(2, chunks[i].offset)
+		meta.structField(3)
+		meta.i32(1, column.typ)
+		meta.list(2, thriftI32, 2)
+		meta.zigzag(parquetPlain)
+		meta.zigzag(parquetRLE)
+		meta.list(3, thriftBinary, 1)
+		meta.varint(uint64(len(column.name)))
+		meta.buf.WriteString(column.name)
+		meta.i32(4, 0)
+		meta.i64(5, int64(rows))
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.end()
+		meta.end()
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(rows))
+	meta.end()
+	meta.str(6, "kubectl-count version "+version)
+	meta.end()
+
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(meta.buf.Len()))
+	body.Write(meta.buf.Bytes())
+	body.Write(size[:])
+	body.WriteString(parquetMagic)
+
+	_, err := w.Write(body.Bytes())
+	return err
+}
+
+func (cc *CounterController) parquetRender(w io.Writer, records []Record) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	stringColumn := func(name string) *parquetColumn {
+		return &parquetColumn{name: name, typ: parquetByteArray, converted: parquetConvertedUTF8}
+	}
+	intColumn := func(name string, converted int32) *parquetColumn {
+		return &parquetColumn{name: name, typ: parquetInt64, converted: converted}
+	}
+
+	timestamp := intColumn("timestamp", parquetConvertedTimestampMillis)
+	cluster := stringColumn("cluster")
+	namespace := stringColumn("namespace")
+	groupVersion := stringColumn("groupVersion")
+	kind := stringColumn("kind")
+	count := intColumn("count", -1)
+
+	breakdownColumns, metricColumns := cc.columns(records)
+	breakdowns := make([]*parquetColumn, 0, len(breakdownColumns))
+	for _, column := range breakdownColumns {
+		breakdowns = append(breakdowns, stringColumn(column))
+	}
+	metrics := make([]*parquetColumn, 0, len(metricColumns))
+	for _, column := range metricColumns {
+		metrics = append(metrics, intColumn(column, -1))
+	}
+
+	for _, record := range records {
+		timestamp.ints = append(timestamp.ints, now)
+		cluster.strings = append(cluster.strings, record.Cluster)
+		namespace.strings = append(namespace.strings, record.Namespace)
+		groupVersion.strings = append(groupVersion.strings, record.GroupVersion)
+		kind.strings = append(kind.strings, record.Kind)
+		count.ints = append(count.ints, int64(record.Count))
+		for i, column := range breakdownColumns {
+			breakdowns[i].strings = append(breakdowns[i].strings, record.Breakdown[column])
+		}
+		for i, column := range metricColumns {
+			metrics[i].ints = append(metrics[i].ints, int64(record.Metrics[column]))
+		}
+	}
+
+	columns := []*parquetColumn{timestamp, cluster, namespace, groupVersion, kind}
+	columns = append(columns, breakdowns...)
+	columns = append(columns, count)
+	columns = append(columns, metrics...)
+	if err := writeParquet(w, columns, len(records)); err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to write Parquet data, error: %v", err)
+		exit(exitFailure)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// thriftReader decodes the subset of the thrift compact protocol written by
+// thriftWriter, structs into maps keyed by field id.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (tr *thriftReader) byte() byte {
+	b := tr.b[tr.pos]
+	tr.pos++
+	return b
+}
+
+func (tr *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(tr.b[tr.pos:])
+	tr.pos += n
+	return v
+}
+
+func (tr *thriftReader) zigzag() int64 {
+	v := tr.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (tr *thriftReader) value(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftI32, thriftI64:
+		return tr.zigzag(), nil
+	case thriftBinary:
+		n := int(tr.varint())
+		s := string(tr.b[tr.pos : tr.pos+n])
+		tr.pos += n
+		return s, nil
+	case thriftList:
+		header := tr.byte()
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(tr.varint())
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			v, err := tr.value(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case thriftStruct:
+		return tr.structValue()
+	}
+	return nil, fmt.Errorf("unexpected thrift type %d at %d", typ, tr.pos)
+}
+
+func (tr *thriftReader) structValue() (map[int16]interface{}, error) {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header := tr.byte()
+		if header == 0 {
+			return fields, nil
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(tr.zigzag())
+		}
+		v, err := tr.value(header & 0x0f)
+		if err != nil {
+			return nil, err
+		}
+		fields[id] = v
+		last = id
+	}
+}
+
+func TestThriftWriterFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(tw *thriftWriter)
+		want  []byte
+	}{
+		{
+			name:  "short field header",
+			write: func(tw *thriftWriter) { tw.i32(1, 1) },
+			want:  []byte{0x15, 0x02, 0x00},
+		},
+		{
+			name:  "negative zigzag",
+			write: func(tw *thriftWriter) { tw.i64(2, -1) },
+			want:  []byte{0x26, 0x01, 0x00},
+		},
+		{
+			name:  "long field header past a delta of 15",
+			write: func(tw *thriftWriter) { tw.i32(1, 0); tw.i32(17, 0) },
+			want:  []byte{0x15, 0x00, 0x05, 0x22, 0x00, 0x00},
+		},
+		{
+			name:  "string",
+			write: func(tw *thriftWriter) { tw.str(4, "ab") },
+			want:  []byte{0x48, 0x02, 'a', 'b', 0x00},
+		},
+		{
+			name:  "long list",
+			write: func(tw *thriftWriter) { tw.list(1, thriftI32, 20) },
+			want:  []byte{0x19, 0xf5, 0x14, 0x00},
+		},
+		{
+			name: "nested struct restarts field ids",
+			write: func(tw *thriftWriter) {
+				tw.i32(3, 0)
+				tw.structField(5)
+				tw.i32(1, 0)
+				tw.end()
+			},
+			want: []byte{0x35, 0x00, 0x2c, 0x15, 0x00, 0x00, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := &thriftWriter{}
+			tw.begin()
+			tt.write(tw)
+			tw.end()
+			if got := tw.buf.Bytes(); !bytes.Equal(got, tt.want) {
+				t.Errorf("wrote % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteParquetMetadata(t *testing.T) {
+	kind := &parquetColumn{name: "kind", typ: parquetByteArray, converted: parquetConvertedUTF8, strings: []string{"Pod", "Secret"}}
+	count := &parquetColumn{name: "count", typ: parquetInt64, converted: -1, ints: []int64{3, 12}}
+	columns := []*parquetColumn{kind, count}
+
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, 2); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	if string(b[:4]) != parquetMagic || string(b[len(b)-4:]) != parquetMagic {
+		t.Fatalf("file isn't framed by %s", parquetMagic)
+	}
+	size := int(binary.LittleEndian.Uint32(b[len(b)-8 : len(b)-4]))
+	footer := b[len(b)-8-size : len(b)-8]
+
+	tr := &thriftReader{b: footer}
+	meta, err := tr.structValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.pos != len(footer) {
+		t.Errorf("decoded %d bytes of a %d bytes footer", tr.pos, len(footer))
+	}
+
+	if meta[1] != int64(1) {
+		t.Errorf("version = %v, want 1", meta[1])
+	}
+	if meta[3] != int64(2) {
+		t.Errorf("num_rows = %v, want 2", meta[3])
+	}
+	if meta[6] != "kubectl-count version "+version {
+		t.Errorf("created_by = %v", meta[6])
+	}
+
+	schema := meta[2].([]interface{})
+	wantSchema := []map[int16]interface{}{
+		{4: "schema", 5: int64(2)},
+		{1: int64(parquetByteArray), 3: int64(parquetRequired), 4: "kind", 6: int64(parquetConvertedUTF8)},
+		{1: int64(parquetInt64), 3: int64(parquetRequired), 4: "count"},
+	}
+	if len(schema) != len(wantSchema) {
+		t.Fatalf("schema has %d elements, want %d", len(schema), len(wantSchema))
+	}
+	for i, element := range schema {
+		if !reflect.DeepEqual(element, wantSchema[i]) {
+			t.Errorf("schema[%d] = %v, want %v", i, element, wantSchema[i])
+		}
+	}
+
+	rowGroups := meta[4].([]interface{})
+	if len(rowGroups) != 1 {
+		t.Fatalf("%d row groups, want 1", len(rowGroups))
+	}
+	rowGroup := rowGroups[0].(map[int16]interface{})
+	if rowGroup[3] != int64(2) {
+		t.Errorf("row group num_rows = %v, want 2", rowGroup[3])
+	}
+
+	var total int64
+	chunks := rowGroup[1].([]interface{})
+	for i, chunk := range chunks {
+		chunk := chunk.(map[int16]interface{})
+		columnMeta := chunk[3].(map[int16]interface{})
+		if path := columnMeta[3].([]interface{}); !reflect.DeepEqual(path, []interface{}{columns[i].name}) {
+			t.Errorf("chunk %d path_in_schema = %v, want [%s]", i, path, columns[i].name)
+		}
+		if columnMeta[1] != int64(columns[i].typ) {
+			t.Errorf("chunk %d type = %v, want %d", i, columnMeta[1], columns[i].typ)
+		}
+		if columnMeta[5] != int64(2) {
+			t.Errorf("chunk %d num_values = %v, want 2", i, columnMeta[5])
+		}
+
+		// the data page of the chunk starts where the metadata says, with
+		// its values plain encoded right after the page header.
+		offset, size := columnMeta[9].(int64), columnMeta[7].(int64)
+		if chunk[2] != offset {
+			t.Errorf("chunk %d file_offset = %v, want %d", i, chunk[2], offset)
+		}
+		page := &thriftReader{b: b[offset : offset+size]}
+		header, err := page.structValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data := b[offset+int64(page.pos) : offset+size]; !bytes.Equal(data, columns[i].plain()) {
+			t.Errorf("chunk %d data = % x, want % x", i, data, columns[i].plain())
+		}
+		if header[2] != int64(len(columns[i].plain())) {
+			t.Errorf("chunk %d page size = %v, want %d", i, header[2], len(columns[i].plain()))
+		}
+		total += size
+	}
+	if rowGroup[2] != total {
+		t.Errorf("row group total_byte_size = %v, want %d", rowGroup[2], total)
+	}
+}
+
+func TestParquetColumnPlain(t *testing.T) {
+	tests := []struct {
+		name   string
+		column parquetColumn
+		want   []byte
+	}{
+		{
+			name:   "int64",
+			column: parquetColumn{typ: parquetInt64, ints: []int64{1, -1}},
+			want:   []byte{1, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name:   "byte array",
+			column: parquetColumn{typ: parquetByteArray, strings: []string{"v1", ""}},
+			want:   []byte{2, 0, 0, 0, 'v', '1', 0, 0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.column.plain(); !bytes.Equal(got, tt.want) {
+				t.Errorf("plain() = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
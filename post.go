@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const postTokenEnv = "KUBECTL_COUNT_POST_TOKEN"
+
+type PostTarget struct {
+	URL     string
+	Token   string
+	Headers http.Header
+}
+
+func NewPostTarget(rawURL, token string, headers []string) (*PostTarget, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid url '%s', want http(s)://host/path", rawURL)
+	}
+
+	// the token is better kept out of the process list and shell history.
+	if token == "" {
+		token = os.Getenv(postTokenEnv)
+	}
+	target := &PostTarget{URL: rawURL, Token: token, Headers: http.Header{}}
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid header '%s', want <name>: <value>", header)
+		}
+		target.Headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return target, nil
+}
+
+func (pt *PostTarget) Post(envelope Envelope) error {
+	b, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, pt.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for name, values := range pt.Headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kubectl-count/"+version)
+	if pt.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+pt.Token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (cc *CounterController) postRecords(records []Record) {
+	if records == nil {
+		records = []Record{}
+	}
+	if err := cc.postTarget.Post(cc.recordEnvelope(records)); err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to post counts to %s, error: %v", cc.postTarget.URL, err)
+		exit(exitFailure)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+func startProfile(profile, output string) error {
+	if output == "" {
+		output = profile + ".pprof"
+	}
+
+	switch profile {
+	case "":
+		return nil
+	case "cpu":
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		onExit(func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	case "mem":
+		onExit(func() {
+			f, err := os.Create(output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to write memory profile, error: %v", err)
+				return
+			}
+			defer f.Close()
+
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to write memory profile, error: %v", err)
+			}
+		})
+	default:
+		return fmt.Errorf("unsupported profile '%s', expected cpu or mem", profile)
+	}
+	return nil
+}
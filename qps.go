@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	maxQPS          = 1000
+	minQPS          = 5
+	qpsRampInterval = 5 * time.Second
+	qpsRampFactor   = 1.25
+)
+
+// adaptiveRateLimiter halves the QPS whenever the apiserver throttles, API
+// Priority and Fairness rejects with 429 once the priority level is out of
+// seats, and ramps it back up while the server keeps up.
+type adaptiveRateLimiter struct {
+	lock        sync.Mutex
+	limiter     *rate.Limiter
+	max         float64
+	lastChanged time.Time
+	stats       *RetryStats
+}
+
+func newAdaptiveRateLimiter(qps float64, stats *RetryStats) *adaptiveRateLimiter {
+	return &adaptiveRateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(qps), int(qps)),
+		max:     qps,
+		stats:   stats,
+	}
+}
+
+func (arl *adaptiveRateLimiter) setQPS(qps float64) {
+	arl.limiter.SetLimit(rate.Limit(qps))
+	arl.limiter.SetBurst(int(qps))
+	arl.lastChanged = time.Now()
+}
+
+func (arl *adaptiveRateLimiter) throttled(resp *http.Response) {
+	arl.lock.Lock()
+	defer arl.lock.Unlock()
+
+	// the requests in flight are rejected together, they lower the QPS once.
+	if time.Since(arl.lastChanged) < time.Second {
+		return
+	}
+	qps := float64(arl.limiter.Limit()) / 2
+	if qps < minQPS {
+		qps = minQPS
+	}
+	arl.setQPS(qps)
+	arl.stats.observeQPS(qps)
+	logger.Info("qps lowered", "qps", qps,
+		"priorityLevel", resp.Header.Get("X-Kubernetes-PF-PriorityLevel-UID"),
+		"flowSchema", resp.Header.Get("X-Kubernetes-PF-FlowSchema-UID"))
+}
+
+func (arl *adaptiveRateLimiter) recover() {
+	arl.lock.Lock()
+	defer arl.lock.Unlock()
+
+	current := float64(arl.limiter.Limit())
+	if current >= arl.max || time.Since(arl.lastChanged) < qpsRampInterval {
+		return
+	}
+	qps := current * qpsRampFactor
+	if qps > arl.max {
+		qps = arl.max
+	}
+	arl.setQPS(qps)
+	logger.Info("qps raised", "qps", qps)
+}
+
+func (arl *adaptiveRateLimiter) TryAccept() bool {
+	arl.recover()
+	return arl.limiter.Allow()
+}
+
+func (arl *adaptiveRateLimiter) Accept() {
+	arl.Wait(context.Background())
+}
+
+func (arl *adaptiveRateLimiter) Wait(ctx context.Context) error {
+	arl.recover()
+	return arl.limiter.Wait(ctx)
+}
+
+func (arl *adaptiveRateLimiter) QPS() float32 {
+	return float32(arl.limiter.Limit())
+}
+
+func (arl *adaptiveRateLimiter) Stop() {}
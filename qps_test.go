@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveRateLimiterThrottled(t *testing.T) {
+	stats := NewRetryStats()
+	arl := newAdaptiveRateLimiter(40, stats)
+	resp := &http.Response{Header: http.Header{}}
+
+	arl.throttled(resp)
+	if qps := arl.QPS(); qps != 20 {
+		t.Fatalf("QPS() = %v after a 429, want 20", qps)
+	}
+
+	// the requests in flight rejected together lower the QPS once.
+	arl.throttled(resp)
+	if qps := arl.QPS(); qps != 20 {
+		t.Fatalf("QPS() = %v after a second 429 within 1s, want 20", qps)
+	}
+
+	for i := 0; i < 5; i++ {
+		arl.lastChanged = time.Now().Add(-time.Second)
+		arl.throttled(resp)
+	}
+	if qps := arl.QPS(); qps != minQPS {
+		t.Errorf("QPS() = %v after many 429s, want %v", qps, minQPS)
+	}
+	if stats.lowestQPS != minQPS {
+		t.Errorf("lowest QPS reported = %v, want %v", stats.lowestQPS, minQPS)
+	}
+}
+
+func TestAdaptiveRateLimiterRecover(t *testing.T) {
+	arl := newAdaptiveRateLimiter(40, NewRetryStats())
+	arl.setQPS(20)
+
+	arl.recover()
+	if qps := arl.QPS(); qps != 20 {
+		t.Fatalf("QPS() = %v right after a change, want 20", qps)
+	}
+
+	arl.lastChanged = time.Now().Add(-qpsRampInterval)
+	arl.recover()
+	if qps := arl.QPS(); qps != 25 {
+		t.Fatalf("QPS() = %v after the ramp interval, want 25", qps)
+	}
+
+	for i := 0; i < 5; i++ {
+		arl.lastChanged = time.Now().Add(-qpsRampInterval)
+		arl.recover()
+	}
+	if qps := arl.QPS(); qps != 40 {
+		t.Errorf("QPS() = %v after ramping up, want the max 40", qps)
+	}
+}
+
+func TestRetryRoundTripperThrottled(t *testing.T) {
+	stats := NewRetryStats()
+	limiter := newAdaptiveRateLimiter(40, stats)
+	rt := &retryRoundTripper{
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+		maxRetries: 3,
+		stats:      stats,
+		limiter:    limiter,
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://apiserver/api/v1/pods", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if qps := limiter.QPS(); qps != 20 {
+		t.Errorf("QPS() = %v after a 429, want 20", qps)
+	}
+}
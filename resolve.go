@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const (
+	resolutionMatched   = "matched"
+	resolutionAmbiguous = "ambiguous"
+	resolutionUnmatched = "unmatched"
+)
+
+type ResolvedResource struct {
+	Group      string `json:"group" yaml:"group"`
+	Version    string `json:"version" yaml:"version"`
+	Resource   string `json:"resource" yaml:"resource"`
+	Kind       string `json:"kind" yaml:"kind"`
+	Namespaced bool   `json:"namespaced" yaml:"namespaced"`
+}
+
+type Resolution struct {
+	Input     string             `json:"input" yaml:"input"`
+	Status    string             `json:"status" yaml:"status"`
+	Resources []ResolvedResource `json:"resources" yaml:"resources"`
+}
+
+func (cc *CounterController) resolve(s string) ([]Resolution, error) {
+	kinds := cc.sanitizeKinds(s)
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("invalid input kind name: '%s'", s)
+	}
+
+	apiResources, err := cc.getApiResources()
+	if err != nil {
+		return nil, err
+	}
+
+	resolutions := make([]Resolution, 0, len(kinds))
+	for _, kind := range kinds {
+		resolution := Resolution{Input: kind, Resources: []ResolvedResource{}}
+		seen := map[string]bool{}
+		for _, ar := range apiResources[kind] {
+			if seen[ar.ID()] {
+				continue
+			}
+			seen[ar.ID()] = true
+			resolution.Resources = append(resolution.Resources, ResolvedResource{
+				Group:      ar.resource.Group,
+				Version:    ar.resource.Version,
+				Resource:   ar.resource.Name,
+				Kind:       ar.resource.Kind,
+				Namespaced: ar.resource.Namespaced,
+			})
+		}
+
+		switch len(resolution.Resources) {
+		case 0:
+			resolution.Status = resolutionUnmatched
+		case 1:
+			resolution.Status = resolutionMatched
+		default:
+			resolution.Status = resolutionAmbiguous
+		}
+		resolutions = append(resolutions, resolution)
+	}
+	return resolutions, nil
+}
+
+func (cc *CounterController) resolutionTableRender(w io.Writer, resolutions []Resolution) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"Input", "Status", "Group", "Version", "Resource", "Kind", "Namespaced"})
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetRowLine(true)
+
+	for _, resolution := range resolutions {
+		if len(resolution.Resources) == 0 {
+			table.Append([]string{resolution.Input, resolution.Status, "", "", "", "", ""})
+			continue
+		}
+		for i, r := range resolution.Resources {
+			status := resolution.Status
+			if i > 0 {
+				status = ""
+			}
+			table.Append([]string{resolution.Input, status, r.Group, r.Version, r.Resource, r.Kind, strconv.FormatBool(r.Namespaced)})
+		}
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderDryRun(kinds, output string) {
+	resolutions, err := cc.resolve(kinds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to resolve kinds, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("ResolutionList", resolutions))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("ResolutionList", resolutions))
+	default:
+		cc.resolutionTableRender(&buf, resolutions)
+	}
+	cc.write(buf.Bytes())
+
+	for _, resolution := range resolutions {
+		if resolution.Status == resolutionUnmatched {
+			exit(exitFailure)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+type RetryStats struct {
+	lock      sync.Mutex
+	retries   map[string]int
+	requests  int
+	throttled int
+	waited    time.Duration
+	lowestQPS float64
+}
+
+func NewRetryStats() *RetryStats {
+	return &RetryStats{
+		retries: map[string]int{},
+	}
+}
+
+func (rs *RetryStats) observeRetry(reason string) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	rs.retries[reason]++
+}
+
+func (rs *RetryStats) observeRequest() {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	rs.requests++
+}
+
+func (rs *RetryStats) Requests() int {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	return rs.requests
+}
+
+func (rs *RetryStats) observeWait(d time.Duration) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	rs.throttled++
+	rs.waited += d
+}
+
+func (rs *RetryStats) observeQPS(qps float64) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if rs.lowestQPS == 0 || qps < rs.lowestQPS {
+		rs.lowestQPS = qps
+	}
+}
+
+func (rs *RetryStats) Report(w io.Writer) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if len(rs.retries) > 0 {
+		total := 0
+		reasons := make([]string, 0, len(rs.retries))
+		for reason, n := range rs.retries {
+			total += n
+			reasons = append(reasons, fmt.Sprintf("%s x%d", reason, n))
+		}
+		sort.Strings(reasons)
+		fmt.Fprintf(w, "[Retry] %d requests retried: %s\n", total, strings.Join(reasons, ", "))
+	}
+	if rs.throttled > 0 {
+		fmt.Fprintf(w, "[Retry] client-side throttling: %d waits, %v in total\n", rs.throttled, rs.waited.Round(time.Millisecond))
+	}
+	if rs.lowestQPS > 0 {
+		fmt.Fprintf(w, "[Retry] the apiserver throttled the requests, QPS lowered down to %.0f\n", rs.lowestQPS)
+	}
+}
+
+type retryRoundTripper struct {
+	next       http.RoundTripper
+	maxRetries int
+	stats      *RetryStats
+	limiter    *adaptiveRateLimiter
+}
+
+// shouldRetry leaves 429 out, client-go already retries it after the
+// Retry-After the apiserver asked for.
+func shouldRetry(resp *http.Response, err error) (string, bool) {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return "", false
+		case errors.Is(err, syscall.ECONNRESET):
+			return "connection reset", true
+		case errors.Is(err, syscall.ECONNREFUSED):
+			return "connection refused", true
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return "eof", true
+		case errors.As(err, &netErr) && netErr.Timeout():
+			return "timeout", true
+		}
+		return "", false
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return strconv.Itoa(resp.StatusCode), true
+	}
+	return "", false
+}
+
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+func (rt *retryRoundTripper) roundTrip(req *http.Request) (*http.Response, error) {
+	rt.stats.observeRequest()
+	resp, err := rt.next.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests && rt.limiter != nil {
+		rt.limiter.throttled(resp)
+	}
+	return resp, err
+}
+
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || rt.maxRetries <= 0 {
+		return rt.roundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := rt.roundTrip(req)
+		reason, retry := shouldRetry(resp, err)
+		if !retry || attempt >= rt.maxRetries {
+			return resp, err
+		}
+
+		delay := retryDelay(resp, attempt)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		rt.stats.observeRetry(reason)
+		logger.Info("request retried", "url", req.URL.Path, "status", reason, "delay", delay.String())
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+type observedRateLimiter struct {
+	flowcontrol.RateLimiter
+	stats *RetryStats
+}
+
+func (orl observedRateLimiter) Wait(ctx context.Context) error {
+	started := time.Now()
+	err := orl.RateLimiter.Wait(ctx)
+	if d := time.Since(started); d > 10*time.Millisecond {
+		orl.stats.observeWait(d)
+	}
+	return err
+}
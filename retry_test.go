@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestShouldRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		err        error
+		wantReason string
+		wantRetry  bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "not found", status: http.StatusNotFound},
+		{name: "too many requests", status: http.StatusTooManyRequests},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, wantReason: "503", wantRetry: true},
+		{name: "gateway timeout", status: http.StatusGatewayTimeout, wantReason: "504", wantRetry: true},
+		{name: "connection reset", err: &url.Error{Op: "Get", Err: syscall.ECONNRESET}, wantReason: "connection reset", wantRetry: true},
+		{name: "connection refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), wantReason: "connection refused", wantRetry: true},
+		{name: "eof", err: &url.Error{Op: "Get", Err: io.EOF}, wantReason: "eof", wantRetry: true},
+		{name: "timeout", err: &url.Error{Op: "Get", Err: timeoutError{}}, wantReason: "timeout", wantRetry: true},
+		{name: "canceled", err: &url.Error{Op: "Get", Err: context.Canceled}},
+		{name: "other error", err: errors.New("x509: certificate signed by unknown authority")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+			reason, retry := shouldRetry(resp, tt.err)
+			if reason != tt.wantReason || retry != tt.wantRetry {
+				t.Errorf("shouldRetry() = %q, %v, want %q, %v", reason, retry, tt.wantReason, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		noResponse bool
+		attempt    int
+		want       time.Duration
+	}{
+		{name: "first attempt", attempt: 0, want: retryBaseDelay},
+		{name: "exponential", attempt: 3, want: 8 * retryBaseDelay},
+		{name: "capped", attempt: 10, want: retryMaxDelay},
+		{name: "overflow", attempt: 100, want: retryMaxDelay},
+		{name: "retry after", retryAfter: "3", attempt: 3, want: 3 * time.Second},
+		{name: "invalid retry after", retryAfter: "soon", attempt: 1, want: 2 * retryBaseDelay},
+		{name: "network error", noResponse: true, attempt: 2, want: 4 * retryBaseDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if !tt.noResponse {
+				resp = &http.Response{Header: http.Header{}}
+				if tt.retryAfter != "" {
+					resp.Header.Set("Retry-After", tt.retryAfter)
+				}
+			}
+			if got := retryDelay(resp, tt.attempt); got != tt.want {
+				t.Errorf("retryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		responses  []int
+		wantCalls  int
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "ok", method: http.MethodGet, responses: []int{200}, wantCalls: 1, wantStatus: 200},
+		{name: "network error then ok", method: http.MethodGet, responses: []int{0, 200}, wantCalls: 2, wantStatus: 200},
+		{name: "5xx then ok", method: http.MethodGet, responses: []int{503, 200}, wantCalls: 2, wantStatus: 200},
+		{name: "too many requests", method: http.MethodGet, responses: []int{429, 200}, wantCalls: 1, wantStatus: 429},
+		{name: "not idempotent", method: http.MethodPost, responses: []int{0, 200}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			rt := &retryRoundTripper{
+				next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					status := tt.responses[calls]
+					calls++
+					if status == 0 {
+						return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: syscall.ECONNRESET}
+					}
+					return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+				}),
+				maxRetries: 3,
+				stats:      NewRetryStats(),
+			}
+
+			req, _ := http.NewRequest(tt.method, "https://apiserver/api/v1/pods", nil)
+			resp, err := rt.RoundTrip(req)
+			if calls != tt.wantCalls {
+				t.Errorf("RoundTrip() made %d calls, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RoundTrip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && resp.StatusCode != tt.wantStatus {
+				t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+const schemaVersion = "v1"
+
+type Envelope struct {
+	SchemaVersion string          `json:"schemaVersion" yaml:"schemaVersion"`
+	Kind          string          `json:"kind" yaml:"kind"`
+	Items         interface{}     `json:"items" yaml:"items"`
+	Errors        []OutputError   `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Warnings      []OutputWarning `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+func newEnvelope(kind string, items interface{}) Envelope {
+	return Envelope{SchemaVersion: schemaVersion, Kind: kind, Items: items}
+}
+
+const jsonSchema = `{
+ "$schema": "https://json-schema.org/draft/2020-12/schema",
+ "$id": "https://github.com/chenjiandongx/kubectl-count/schema/v1.json",
+ "title": "kubectl-count output",
+ "description": "Documents printed by -o json and -o yaml are envelopes, -o grafana prints a list of tables, -o parquet and -o sql write one row per record.",
+ "oneOf": [
+  {"$ref": "#/$defs/recordList"},
+  {"$ref": "#/$defs/churnRecordList"},
+  {"$ref": "#/$defs/resolutionList"},
+  {"$ref": "#/$defs/explanationList"},
+  {"$ref": "#/$defs/apiGroupList"},
+  {"$ref": "#/$defs/snapshot"},
+  {"$ref": "#/$defs/diffList"},
+  {"$ref": "#/$defs/comparisonList"},
+  {"$ref": "#/$defs/driftList"},
+  {"$ref": "#/$defs/subjectList"},
+  {"$ref": "#/$defs/coverageList"},
+  {"$ref": "#/$defs/sliceStatsList"},
+  {"$ref": "#/$defs/crdVersionList"},
+  {"$ref": "#/$defs/histogramBucketList"},
+  {"$ref": "#/$defs/duplicateNameList"},
+  {"$ref": "#/$defs/namespaceKindsList"},
+  {"$ref": "#/$defs/topNamespaceList"},
+  {"$ref": "#/$defs/benchResultList"},
+  {"$ref": "#/$defs/grafanaTables"}
+ ],
+ "$defs": {
+  "recordList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "RecordList"},
+    "items": {"type": "array", "items": {"$ref": "#/$defs/record"}},
+    "errors": {
+     "type": "array",
+     "description": "failures with -o json and yaml, the items are partial or empty when set",
+     "items": {
+      "type": "object",
+      "required": ["reason", "message"],
+      "properties": {
+       "reason": {"enum": ["Failed", "AccessDenied", "Interrupted"]},
+       "cluster": {"type": "string", "description": "cluster which failed in multi-cluster mode"},
+       "message": {"type": "string"}
+      }
+     }
+    },
+    "warnings": {"$ref": "#/$defs/warnings"}
+   }
+  },
+  "warnings": {
+   "type": "array",
+   "description": "non-fatal issues with -o json and yaml, --strict turns them into errors",
+   "items": {
+    "type": "object",
+    "required": ["reason", "message"],
+    "properties": {
+     "reason": {"enum": ["DiscoveryFailed", "KindNotFound", "StaleDiscovery", "Approximate", "MemoryPressure", "Timeout", "ServerWarning", "CRDsUnavailable"]},
+     "message": {"type": "string"}
+    }
+   }
+  },
+  "record": {
+   "type": "object",
+   "required": ["namespace", "groupVersion", "kind", "count"],
+   "properties": {
+    "cluster": {"type": "string", "description": "cluster the record was counted in, only set in multi-cluster mode"},
+    "namespace": {"type": "string", "description": "empty for cluster scoped kinds and with --all-namespaces"},
+    "groupVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "breakdown": {"type": "object", "additionalProperties": {"type": "string"}, "description": "status the count is split by with --breakdown"},
+    "count": {"type": "integer", "minimum": 0},
+    "metrics": {"type": "object", "additionalProperties": {"type": "integer"}, "description": "values reported by analyzers such as --managed-fields"},
+    "averages": {"type": "object", "additionalProperties": {"type": "number"}, "description": "metrics per counted object rounded to 2 decimals, such as the containers per pod of --containers"},
+    "approximate": {"type": "boolean", "description": "counted with a paged list after the informer of the kind failed to sync"},
+    "change": {
+     "type": "object",
+     "description": "only set by watch, the count when the watch started and the percentage changed since",
+     "required": ["since", "percent"],
+     "properties": {
+      "since": {"type": "integer", "minimum": 0},
+      "percent": {"type": "number"}
+     }
+    }
+   }
+  },
+  "churnRecordList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "ChurnRecordList"},
+    "items": {"type": "array", "items": {"$ref": "#/$defs/churnRecord"}}
+   }
+  },
+  "churnRecord": {
+   "type": "object",
+   "required": ["namespace", "groupVersion", "kind", "addsPerMinute", "updatesPerMinute", "deletesPerMinute"],
+   "properties": {
+    "namespace": {"type": "string"},
+    "groupVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "addsPerMinute": {"type": "number", "minimum": 0},
+    "updatesPerMinute": {"type": "number", "minimum": 0},
+    "deletesPerMinute": {"type": "number", "minimum": 0}
+   }
+  },
+  "resolutionList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "ResolutionList"},
+    "items": {"type": "array", "items": {"$ref": "#/$defs/resolution"}}
+   }
+  },
+  "resolution": {
+   "type": "object",
+   "required": ["input", "status", "resources"],
+   "properties": {
+    "input": {"type": "string"},
+    "status": {"enum": ["matched", "ambiguous", "unmatched"]},
+    "resources": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["group", "version", "resource", "kind", "namespaced"],
+      "properties": {
+       "group": {"type": "string"},
+       "version": {"type": "string"},
+       "resource": {"type": "string"},
+       "kind": {"type": "string"},
+       "namespaced": {"type": "boolean"}
+      }
+     }
+    }
+   }
+  },
+  "explanationList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "ExplanationList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["input", "candidates"],
+      "properties": {
+       "input": {"type": "string"},
+       "candidates": {
+        "type": "array",
+        "items": {
+         "type": "object",
+         "required": ["group", "version", "resource", "kind", "namespaced", "matchedBy", "categories", "counted", "preferred"],
+         "properties": {
+          "group": {"type": "string"},
+          "version": {"type": "string"},
+          "resource": {"type": "string"},
+          "kind": {"type": "string"},
+          "namespaced": {"type": "boolean"},
+          "matchedBy": {"type": "array", "items": {"enum": ["name", "kind", "name.group", "shortName", "singularName", "category"]}},
+          "categories": {"type": "array", "items": {"type": "string"}},
+          "counted": {"type": "boolean"},
+          "preferred": {"type": "boolean"}
+         }
+        }
+       }
+      }
+     }
+    }
+   }
+  },
+  "apiGroupList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "APIGroupList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["groupVersion", "source", "resources"],
+      "properties": {
+       "groupVersion": {"type": "string"},
+       "source": {"enum": ["built-in", "crd", "aggregated", "unknown"], "description": "unknown when the CustomResourceDefinitions can't be listed and the group version isn't aggregated"},
+       "resources": {"type": "integer", "minimum": 0}
+      }
+     }
+    },
+    "warnings": {"$ref": "#/$defs/warnings"}
+   }
+  },
+  "snapshot": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "collectedAt", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "Snapshot"},
+    "collectedAt": {"type": "string", "format": "date-time"},
+    "context": {"type": "string"},
+    "scope": {
+     "type": "object",
+     "description": "namespace scope the counts were taken in, diff counts the live ones in the same scope",
+     "properties": {
+      "allNamespaces": {"type": "boolean"},
+      "namespace": {"type": "string"}
+     }
+    },
+    "items": {"type": "array", "items": {"$ref": "#/$defs/record"}}
+   }
+  },
+  "diffList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "DiffList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["namespace", "groupVersion", "kind", "before", "after", "change"],
+      "properties": {
+       "cluster": {"type": "string"},
+       "namespace": {"type": "string"},
+       "groupVersion": {"type": "string"},
+       "kind": {"type": "string"},
+       "breakdown": {"type": "object", "additionalProperties": {"type": "string"}},
+       "before": {"type": "integer", "minimum": 0},
+       "after": {"type": "integer", "minimum": 0},
+       "change": {"type": "integer"}
+      }
+     }
+    }
+   }
+  },
+  "comparisonList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "ComparisonList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["groupVersion", "kind", "counts", "delta"],
+      "properties": {
+       "namespace": {"type": "string", "description": "set when contexts are compared without --all-namespaces"},
+       "groupVersion": {"type": "string"},
+       "kind": {"type": "string"},
+       "breakdown": {"type": "object", "additionalProperties": {"type": "string"}},
+       "counts": {"type": "object", "additionalProperties": {"type": "integer"}, "description": "counts keyed by the compared namespaces or contexts"},
+       "delta": {"type": "integer", "description": "count of the last compared minus the first"},
+       "forbidden": {"type": "array", "items": {"type": "string"}, "description": "only set by compare-as, the identities which can't list the kind in any namespace"}
+      }
+     }
+    }
+   }
+  },
+  "driftList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "DriftList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["kind", "expected", "actual", "drifted"],
+      "properties": {
+       "namespace": {"type": "string"},
+       "groupVersion": {"type": "string"},
+       "kind": {"type": "string"},
+       "expected": {"type": "integer", "minimum": 0},
+       "actual": {"type": "integer", "minimum": 0},
+       "tolerance": {"type": "string", "description": "absolute like 3 or relative to expected like 10%"},
+       "drifted": {"type": "boolean"}
+      }
+     }
+    }
+   }
+  },
+  "subjectList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "SubjectList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["kind", "name", "roleBindings", "clusterRoleBindings"],
+      "properties": {
+       "kind": {"type": "string", "description": "User, Group or ServiceAccount"},
+       "name": {"type": "string"},
+       "namespace": {"type": "string", "description": "set for service accounts only"},
+       "roleBindings": {"type": "integer", "minimum": 0},
+       "clusterRoleBindings": {"type": "integer", "minimum": 0}
+      }
+     }
+    }
+   }
+  },
+  "coverageList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "CoverageList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["namespace", "groupVersion", "kind", "covered", "uncovered"],
+      "properties": {
+       "namespace": {"type": "string"},
+       "groupVersion": {"type": "string"},
+       "kind": {"type": "string"},
+       "covered": {"type": "integer", "minimum": 0},
+       "uncovered": {"type": "integer", "minimum": 0}
+      }
+     }
+    }
+   }
+  },
+  "sliceStatsList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "SliceStatsList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["namespace", "services", "endpointSlices", "min", "avg", "max"],
+      "properties": {
+       "namespace": {"type": "string"},
+       "services": {"type": "integer", "minimum": 0},
+       "endpointSlices": {"type": "integer", "minimum": 0},
+       "min": {"type": "integer", "minimum": 0},
+       "avg": {"type": "number", "minimum": 0},
+       "max": {"type": "integer", "minimum": 0},
+       "maxService": {"type": "string", "description": "service with the most endpointslices, prefixed by its namespace with --all-namespaces"}
+      }
+     }
+    }
+   }
+  },
+  "crdVersionList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "CRDVersionList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["crd", "version", "served", "storage", "stored", "count"],
+      "properties": {
+       "crd": {"type": "string"},
+       "version": {"type": "string"},
+       "served": {"type": "boolean"},
+       "storage": {"type": "boolean", "description": "version new objects are persisted in"},
+       "stored": {"type": "boolean", "description": "version listed in status.storedVersions, objects may still be persisted in it"},
+       "count": {"type": "integer", "minimum": 0, "description": "objects listed through this version, 0 for versions which aren't served"},
+       "error": {"type": "string"}
+      }
+     }
+    }
+   }
+  },
+  "histogramBucketList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "HistogramBucketList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["created", "count"],
+      "properties": {
+       "created": {"type": "string", "description": "local creation hour '2006-01-02 15:00' or day '2006-01-02'"},
+       "count": {"type": "integer", "minimum": 0}
+      }
+     }
+    }
+   }
+  },
+  "duplicateNameList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "DuplicateNameList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["groupVersion", "kind", "name", "count", "namespaces"],
+      "properties": {
+       "groupVersion": {"type": "string"},
+       "kind": {"type": "string"},
+       "name": {"type": "string"},
+       "count": {"type": "integer", "minimum": 2},
+       "namespaces": {"type": "array", "items": {"type": "string"}}
+      }
+     }
+    }
+   }
+  },
+  "namespaceKindsList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "NamespaceKindsList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["namespace", "count", "kinds"],
+      "properties": {
+       "namespace": {"type": "string"},
+       "count": {"type": "integer", "minimum": 1},
+       "kinds": {"type": "array", "items": {"type": "string", "description": "Kind.groupVersion"}}
+      }
+     }
+    }
+   }
+  },
+  "topNamespaceList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "TopNamespaceList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["namespace", "count", "kinds"],
+      "properties": {
+       "namespace": {"type": "string"},
+       "count": {"type": "integer", "minimum": 1},
+       "kinds": {
+        "type": "array",
+        "description": "largest kinds first",
+        "items": {
+         "type": "object",
+         "required": ["groupVersion", "kind", "count"],
+         "properties": {
+          "groupVersion": {"type": "string"},
+          "kind": {"type": "string"},
+          "count": {"type": "integer", "minimum": 1}
+         }
+        }
+       }
+      }
+     }
+    }
+   }
+  },
+  "benchResultList": {
+   "type": "object",
+   "required": ["schemaVersion", "kind", "items"],
+   "properties": {
+    "schemaVersion": {"const": "v1"},
+    "kind": {"const": "BenchResultList"},
+    "items": {
+     "type": "array",
+     "items": {
+      "type": "object",
+      "required": ["strategy", "objects", "durationSeconds", "peakMemoryBytes", "allocatedBytes", "requests"],
+      "properties": {
+       "strategy": {"enum": ["informer", "paged-list", "metadata", "fast"]},
+       "objects": {"type": "integer", "minimum": 0},
+       "durationSeconds": {"type": "number", "minimum": 0},
+       "peakMemoryBytes": {"type": "integer", "minimum": 0, "description": "peak of the heap in use above the one before the strategy ran"},
+       "allocatedBytes": {"type": "integer", "minimum": 0},
+       "requests": {"type": "integer", "minimum": 0},
+       "error": {"type": "string", "description": "set when the strategy failed, objects is 0 then"}
+      }
+     }
+    }
+   }
+  },
+  "streamRecord": {
+   "description": "one line of watch --stream, which prints NDJSON rather than envelopes",
+   "type": "object",
+   "required": ["timestamp", "namespace", "groupVersion", "kind", "count"],
+   "properties": {
+    "timestamp": {"type": "string", "format": "date-time"},
+    "namespace": {"type": "string"},
+    "groupVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "breakdown": {"type": "object", "additionalProperties": {"type": "string"}},
+    "count": {"type": "integer", "minimum": 0}
+   }
+  },
+  "grafanaTables": {
+   "type": "array",
+   "items": {
+    "type": "object",
+    "required": ["type", "columns", "rows"],
+    "properties": {
+     "type": {"const": "table"},
+     "columns": {
+      "type": "array",
+      "items": {
+       "type": "object",
+       "required": ["text", "type"],
+       "properties": {
+        "text": {"type": "string"},
+        "type": {"enum": ["time", "string", "number"]}
+       }
+      }
+     },
+     "rows": {"type": "array", "items": {"type": "array"}}
+    }
+   }
+  },
+  "row": {
+   "type": "object",
+   "description": "a row of -o parquet and -o sql, breakdown and metrics are flattened into columns by parquet and kept as JSON by sql",
+   "required": ["timestamp", "cluster", "namespace", "groupVersion", "kind", "count"],
+   "properties": {
+    "timestamp": {"type": "string", "format": "date-time"},
+    "cluster": {"type": "string"},
+    "namespace": {"type": "string"},
+    "groupVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "count": {"type": "integer", "minimum": 0}
+   }
+  }
+ }
+}`
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the machine-readable output formats.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(cmd.OutOrStdout(), jsonSchema)
+	},
+}
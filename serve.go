@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve <kinds>",
+	Short: "Serve the resources counts as Prometheus metrics.",
+	Example: `  # expose the counts of pods and deployments on :8080/metrics and :8080/records.
+  kubectl count serve pods,deploy
+
+  # serve the counts of jobs by status through the external metrics API for HPAs.
+  kubectl count serve -A --breakdown --external-metrics --listen-address :6443 jobs`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		address, _ := cmd.Flags().GetString("listen-address")
+		externalMetrics, _ := cmd.Flags().GetBool("external-metrics")
+		certFile, _ := cmd.Flags().GetString("tls-cert-file")
+		keyFile, _ := cmd.Flags().GetString("tls-private-key-file")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+		mustController(cmd).Serve(mustKinds(cmd, args), allNamespace, ServeOptions{
+			Address:         address,
+			ExternalMetrics: externalMetrics,
+			CertFile:        certFile,
+			KeyFile:         keyFile,
+		})
+		exit(0)
+	},
+}
+
+type ServeOptions struct {
+	Address         string
+	ExternalMetrics bool
+	CertFile        string
+	KeyFile         string
+}
+
+var promLabelInvalid = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func promLabel(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return fmt.Sprintf(`%s="%s"`, promLabelInvalid.ReplaceAllString(name, "_"), value)
+}
+
+func promRender(w io.Writer, records []Record) {
+	fmt.Fprintf(w, "# HELP %s Number of resources by namespace and kind.\n", metricName)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", metricName)
+	for _, record := range records {
+		var labels []string
+		if record.Cluster != "" {
+			labels = append(labels, promLabel("cluster", record.Cluster))
+		}
+		labels = append(labels,
+			promLabel("namespace", record.Namespace),
+			promLabel("group_version", record.GroupVersion),
+			promLabel("kind", record.Kind),
+		)
+
+		keys := make([]string, 0, len(record.Breakdown))
+		for key := range record.Breakdown {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			labels = append(labels, promLabel(key, record.Breakdown[key]))
+		}
+		fmt.Fprintf(w, "%s{%s} %d\n", metricName, strings.Join(labels, ","), record.Count)
+	}
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (cc *CounterController) writeTextfile(records []Record) {
+	var buf bytes.Buffer
+	promRender(&buf, records)
+	fmt.Fprintln(&buf, "# HELP kubectl_count_last_run_timestamp_seconds Unix time the counts were collected at.")
+	fmt.Fprintln(&buf, "# TYPE kubectl_count_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(&buf, "kubectl_count_last_run_timestamp_seconds %d\n", time.Now().Unix())
+
+	// node_exporter may read the file at any time, never let it see a
+	// partially written one.
+	if err := writeFileAtomic(cc.textfile, buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to write textfile, error: %v", err)
+		exit(exitFailure)
+	}
+}
+
+func (cc *CounterController) Serve(kinds string, allNamespace bool, opts ServeOptions) {
+	// the server comes up before the caches sync so the probes can tell a
+	// process still listing from a dead one.
+	ready := make(chan struct{})
+	isReady := func() bool {
+		select {
+		case <-ready:
+			return cc.ctx.Err() == nil
+		default:
+			return false
+		}
+	}
+
+	mux := http.NewServeMux()
+	routes := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !isReady() {
+			http.Error(w, "caches not synced", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if !isReady() {
+			http.Error(w, "caches not synced", http.StatusServiceUnavailable)
+			return
+		}
+		routes.ServeHTTP(w, r)
+	})
+
+	server := &http.Server{Addr: opts.Address, Handler: mux}
+	go func() {
+		<-cc.ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	serve := server.ListenAndServe
+	if opts.ExternalMetrics {
+		// the aggregator only talks TLS, fall back to a self-signed certificate
+		// for APIServices with insecureSkipTLSVerify.
+		if opts.CertFile == "" {
+			cert, err := selfSignedCertificate()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to generate certificate, error: %v", err)
+				exit(exitFailure)
+			}
+			server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		}
+		serve = func() error {
+			return server.ListenAndServeTLS(opts.CertFile, opts.KeyFile)
+		}
+	}
+
+	logger.Info("serving metrics", "address", opts.Address, "externalMetrics", opts.ExternalMetrics)
+	// a busy address fails right away rather than once the caches synced.
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to serve metrics, error: %v", err)
+			exit(exitFailure)
+		}
+	}()
+
+	idMap, err := cc.sync(kinds, nil)
+	cc.summarize()
+	if errors.Is(err, errInterrupted) {
+		exit(exitInterrupted)
+	}
+	if err != nil {
+		logger.Error("watch resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to watch resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	routes.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		promRender(w, idMap.GetRecords("asc", allNamespace))
+	})
+	routes.HandleFunc("/records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		cc.jsonRender(w, newEnvelope("RecordList", cc.withAverages(idMap.GetRecords("asc", allNamespace))))
+	})
+	if opts.ExternalMetrics {
+		cc.externalMetricsRoutes(routes, idMap, allNamespace)
+	}
+	close(ready)
+	<-served
+}
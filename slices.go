@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+type SliceStats struct {
+	Namespace      string  `json:"namespace" yaml:"namespace"`
+	Services       int     `json:"services" yaml:"services"`
+	EndpointSlices int     `json:"endpointSlices" yaml:"endpointSlices"`
+	Min            int     `json:"min" yaml:"min"`
+	Avg            float64 `json:"avg" yaml:"avg"`
+	Max            int     `json:"max" yaml:"max"`
+	MaxService     string  `json:"maxService,omitempty" yaml:"maxService,omitempty"`
+}
+
+var endpointSlicesCmd = &cobra.Command{
+	Use:   "endpointslices",
+	Short: "Report the minimum, average and maximum EndpointSlices per Service.",
+	Example: `  # find the services with the most endpointslices of the cluster.
+  kubectl count endpointslices -A
+
+  # compare namespaces, the ones with the largest maximum first.
+  kubectl count endpointslices -O desc`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		order, _ := cmd.Flags().GetString("order")
+		format, _ := cmd.Flags().GetString("output-format")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+		namespace, _ := cmd.Flags().GetString("namespace")
+
+		ctr := mustController(cmd)
+		stats, err := ctr.sliceStats(namespace, order, allNamespace)
+		if err != nil {
+			logger.Error("list resources failed", err)
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+			exit(exitCode(err))
+		}
+		ctr.RenderSliceStats(stats, format)
+		exit(0)
+	},
+}
+
+func (cc *CounterController) sliceStats(namespace, order string, allNamespace bool) ([]SliceStats, error) {
+	_, services, err := cc.listResource("services", namespace)
+	if err != nil {
+		return nil, err
+	}
+	_, slices, err := cc.listResource("endpointslices.discovery.k8s.io", namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// services without a slice count as 0, slices of services which are
+	// gone still count for their service name.
+	counts := map[string]map[string]int{}
+	observe := func(namespace, name string, n int) {
+		if counts[namespace] == nil {
+			counts[namespace] = map[string]int{}
+		}
+		counts[namespace][name] += n
+	}
+	for _, service := range services {
+		observe(service.GetNamespace(), service.GetName(), 0)
+	}
+	for _, slice := range slices {
+		name := slice.GetLabels()["kubernetes.io/service-name"]
+		if name == "" {
+			continue
+		}
+		observe(slice.GetNamespace(), name, 1)
+	}
+
+	namespaces := make([]string, 0, len(counts))
+	for ns := range counts {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	stats := map[string]*SliceStats{}
+	var keys []string
+	for _, ns := range namespaces {
+		key := ns
+		if allNamespace {
+			key = ""
+		}
+		s, ok := stats[key]
+		if !ok {
+			s = &SliceStats{Namespace: key, Min: -1}
+			stats[key] = s
+			keys = append(keys, key)
+		}
+
+		names := make([]string, 0, len(counts[ns]))
+		for name := range counts[ns] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			n := counts[ns][name]
+			s.Services++
+			s.EndpointSlices += n
+			if s.Min < 0 || n < s.Min {
+				s.Min = n
+			}
+			if n > s.Max || s.MaxService == "" {
+				s.Max = n
+				s.MaxService = name
+				if allNamespace {
+					s.MaxService = ns + "/" + name
+				}
+			}
+		}
+	}
+
+	ret := make([]SliceStats, 0, len(keys))
+	for _, key := range keys {
+		s := stats[key]
+		if s.Services > 0 {
+			s.Avg = float64(s.EndpointSlices) / float64(s.Services)
+		}
+		ret = append(ret, *s)
+	}
+
+	desc := isDesc(order)
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Max != ret[j].Max {
+			if desc {
+				return ret[i].Max > ret[j].Max
+			}
+			return ret[i].Max < ret[j].Max
+		}
+		return ret[i].Namespace < ret[j].Namespace
+	})
+	return ret, nil
+}
+
+func (cc *CounterController) sliceStatsTableRender(w io.Writer, stats []SliceStats) {
+	headers := []string{"Namespace", "Services", "EndpointSlices", "Min", "Avg", "Max", "MaxService"}
+	table := cc.newTable(w)
+	table.SetHeader(headers)
+	table.SetAutoFormatHeaders(false)
+	table.SetRowLine(true)
+
+	for _, s := range stats {
+		table.Append([]string{
+			s.Namespace,
+			humanizeCount(s.Services, cc.humanize),
+			humanizeCount(s.EndpointSlices, cc.humanize),
+			humanizeCount(s.Min, cc.humanize),
+			fmt.Sprintf("%.2f", s.Avg),
+			humanizeCount(s.Max, cc.humanize),
+			s.MaxService,
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderSliceStats(stats []SliceStats, output string) {
+	if len(stats) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("SliceStatsList", stats))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("SliceStatsList", stats))
+	default:
+		cc.sliceStatsTableRender(&buf, stats)
+	}
+	cc.write(buf.Bytes())
+}
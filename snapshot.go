@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type Snapshot struct {
+	SchemaVersion string         `json:"schemaVersion" yaml:"schemaVersion"`
+	Kind          string         `json:"kind" yaml:"kind"`
+	CollectedAt   time.Time      `json:"collectedAt" yaml:"collectedAt"`
+	Context       string         `json:"context,omitempty" yaml:"context,omitempty"`
+	Scope         *SnapshotScope `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Items         []Record       `json:"items" yaml:"items"`
+}
+
+// SnapshotScope is the namespace scope the counts were taken in, rows summed
+// over namespaces with -A can only be compared with rows summed the same way.
+type SnapshotScope struct {
+	AllNamespaces bool   `json:"allNamespaces,omitempty" yaml:"allNamespaces,omitempty"`
+	Namespace     string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
+// snapshots taken before the scope was recorded match any scope.
+func sameScope(a, b *SnapshotScope) bool {
+	return a == nil || b == nil || *a == *b
+}
+
+type DiffRecord struct {
+	Cluster      string            `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Namespace    string            `json:"namespace" yaml:"namespace"`
+	GroupVersion string            `json:"groupVersion" yaml:"groupVersion"`
+	Kind         string            `json:"kind" yaml:"kind"`
+	Breakdown    map[string]string `json:"breakdown,omitempty" yaml:"breakdown,omitempty"`
+	Before       int               `json:"before" yaml:"before"`
+	After        int               `json:"after" yaml:"after"`
+	Change       int               `json:"change" yaml:"change"`
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot <kinds>",
+	Short: "Count resources by kind and save the counts for a later diff.",
+	Example: `  # save the counts of pods and deployments of every namespace.
+  kubectl count snapshot -A --output-file before.json pods,deploy
+
+  # keep a history of the counts in a bucket, under a timestamped key per run.
+  kubectl count snapshot -A --upload s3://inventory/prod-eu/ pods,deploy`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		order, _ := cmd.Flags().GetString("order")
+		format, _ := cmd.Flags().GetString("output-format")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+
+		// a bad destination fails before the counting, not after.
+		var target *UploadTarget
+		if dest, _ := cmd.Flags().GetString("upload"); dest != "" {
+			var err error
+			target, err = NewUploadTarget(dest)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Invalid --upload destination, error: %v", err)
+				exit(exitFailure)
+			}
+		}
+		ctr := mustController(cmd)
+		ctr.uploadTarget = target
+		ctr.Snapshot(mustKinds(cmd, args), order, format, allNamespace)
+		exit(0)
+	},
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <snapshot> [snapshot]",
+	Short: "Compare a snapshot with another one or with the live counts.",
+	Example: `  # compare a snapshot with the counts of the same kinds in the cluster right now,
+  # counted in the namespace scope of the snapshot.
+  kubectl count diff before.json
+
+  # compare two snapshots.
+  kubectl count diff before.json after.json`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		order, _ := cmd.Flags().GetString("order")
+		format, _ := cmd.Flags().GetString("output-format")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+
+		before, err := loadSnapshot(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to load snapshot %s, error: %v", args[0], err)
+			exit(exitFailure)
+		}
+
+		// two snapshots are compared offline without connecting to a cluster.
+		var ctr *CounterController
+		var after []Record
+		if len(args) == 2 {
+			snapshot, err := loadSnapshot(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to load snapshot %s, error: %v", args[1], err)
+				exit(exitFailure)
+			}
+			if !sameScope(before.Scope, snapshot.Scope) {
+				fmt.Fprintf(os.Stderr, "[Oh...] Snapshots %s and %s were taken in different namespace scopes!\n", args[0], args[1])
+				exit(exitFailure)
+			}
+			after = snapshot.Items
+			ctr = &CounterController{}
+			ctr.configure(cmd)
+		} else {
+			namespace, _ := cmd.Flags().GetString("namespace")
+			if before.Scope != nil {
+				allNamespace, namespace = before.Scope.AllNamespaces, before.Scope.Namespace
+			}
+			ctr, err = newNamespacedController(cmd, cf, namespace)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to Create Controller, error: %v", err)
+				exit(exitFailure)
+			}
+			after, err = ctr.liveRecords(before.Items, order, allNamespace)
+			if err != nil {
+				logger.Error("list resources failed", err)
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+				exit(exitCode(err))
+			}
+		}
+
+		ctr.RenderDiff(diffRecords(before.Items, after, order), format)
+		exit(0)
+	},
+}
+
+func currentContext() string {
+	if *cf.Context != "" {
+		return *cf.Context
+	}
+	rawConfig, err := cf.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return rawConfig.CurrentContext
+}
+
+func loadSnapshot(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		err = json.Unmarshal(b, snapshot)
+	} else {
+		err = yaml.Unmarshal(b, snapshot)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Kind != "Snapshot" {
+		return nil, fmt.Errorf("unexpected kind '%s', want Snapshot", snapshot.Kind)
+	}
+	return snapshot, nil
+}
+
+func (cc *CounterController) Snapshot(kinds, order, output string, allNamespace bool) {
+	records, err := cc.Records(kinds, order, allNamespace)
+	if err != nil {
+		logger.Error("list resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	snapshot := Snapshot{
+		SchemaVersion: schemaVersion,
+		Kind:          "Snapshot",
+		CollectedAt:   time.Now().UTC(),
+		Context:       currentContext(),
+		Scope:         &SnapshotScope{AllNamespaces: allNamespace, Namespace: cc.namespace},
+		Items:         records,
+	}
+
+	var buf bytes.Buffer
+	ext, contentType := "json", "application/json"
+	switch output {
+	case "yaml", "y":
+		ext, contentType = "yaml", "application/yaml"
+		cc.yamlRender(&buf, snapshot)
+	default:
+		cc.jsonRender(&buf, snapshot)
+	}
+	cc.write(buf.Bytes())
+
+	// an interrupted run would land in the history like a complete one.
+	if cc.uploadTarget != nil && cc.partial() {
+		fmt.Fprintln(os.Stderr, "[Upload] snapshot not uploaded, the run was interrupted before all kinds were counted")
+	} else if cc.uploadTarget != nil {
+		dest := cc.uploadTarget.URL(snapshot.CollectedAt, ext)
+		if err := cc.uploadTarget.Upload(buf.Bytes(), dest, contentType); err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to upload snapshot to %s, error: %v", dest, err)
+			exit(exitFailure)
+		}
+		fmt.Fprintf(os.Stderr, "[Upload] snapshot uploaded to %s\n", dest)
+	}
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
+
+func (cc *CounterController) liveRecords(before []Record, order string, allNamespace bool) ([]Record, error) {
+	var kinds []string
+	ids := map[string]bool{}
+	for _, record := range before {
+		id := record.Kind + "+" + record.GroupVersion
+		if !ids[id] {
+			ids[id] = true
+			kinds = append(kinds, strings.ToLower(record.Kind))
+		}
+	}
+
+	records, err := cc.Records(strings.Join(kinds, ","), order, allNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// lowercase kinds may match the same kind of other groups, only keep what
+	// the snapshot has.
+	ret := make([]Record, 0, len(records))
+	for _, record := range records {
+		if ids[record.Kind+"+"+record.GroupVersion] {
+			ret = append(ret, record)
+		}
+	}
+	return ret, nil
+}
+
+func diffKey(record Record) string {
+	return strings.Join([]string{record.Cluster, record.Namespace, record.GroupVersion, record.Kind, Sample{Breakdown: record.Breakdown}.key()}, "|")
+}
+
+func diffRecords(before, after []Record, order string) []DiffRecord {
+	var keys []string
+	diffs := map[string]*DiffRecord{}
+	observe := func(record Record) *DiffRecord {
+		key := diffKey(record)
+		if _, ok := diffs[key]; !ok {
+			keys = append(keys, key)
+			diffs[key] = &DiffRecord{
+				Cluster:      record.Cluster,
+				Namespace:    record.Namespace,
+				GroupVersion: record.GroupVersion,
+				Kind:         record.Kind,
+				Breakdown:    record.Breakdown,
+			}
+		}
+		return diffs[key]
+	}
+	for _, record := range before {
+		observe(record).Before += record.Count
+	}
+	for _, record := range after {
+		observe(record).After += record.Count
+	}
+
+	ret := make([]DiffRecord, 0)
+	for _, key := range keys {
+		diff := diffs[key]
+		diff.Change = diff.After - diff.Before
+		if diff.Change != 0 {
+			ret = append(ret, *diff)
+		}
+	}
+
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	desc := isDesc(order)
+	sort.SliceStable(ret, func(i, j int) bool {
+		if abs(ret[i].Change) != abs(ret[j].Change) {
+			if desc {
+				return abs(ret[i].Change) > abs(ret[j].Change)
+			}
+			return abs(ret[i].Change) < abs(ret[j].Change)
+		}
+		if ret[i].Namespace != ret[j].Namespace {
+			return ret[i].Namespace < ret[j].Namespace
+		}
+		return ret[i].Kind < ret[j].Kind
+	})
+	return ret
+}
+
+func (cc *CounterController) diffTableRender(w io.Writer, diffs []DiffRecord) {
+	records := make([]Record, 0, len(diffs))
+	withCluster := false
+	for _, diff := range diffs {
+		records = append(records, Record{Breakdown: diff.Breakdown})
+		if diff.Cluster != "" {
+			withCluster = true
+		}
+	}
+	breakdownColumns, _ := cc.columns(records)
+
+	headers := []string{"Namespace", "GroupVersion", "Kind"}
+	if withCluster {
+		headers = append([]string{"Cluster"}, headers...)
+	}
+	for _, column := range breakdownColumns {
+		headers = append(headers, strings.ToUpper(column[:1])+column[1:])
+	}
+	headers = append(headers, "Before", "After", "Change")
+
+	table := cc.newTable(w)
+	table.SetHeader(headers)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+
+	for _, diff := range diffs {
+		row := []string{diff.Namespace, diff.GroupVersion, diff.Kind}
+		if withCluster {
+			row = append([]string{diff.Cluster}, row...)
+		}
+		for _, column := range breakdownColumns {
+			row = append(row, diff.Breakdown[column])
+		}
+		row = append(row,
+			humanizeCount(diff.Before, cc.humanize),
+			humanizeCount(diff.After, cc.humanize),
+			fmt.Sprintf("%+d", diff.Change),
+		)
+		table.Append(row)
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderDiff(diffs []DiffRecord, output string) {
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("DiffList", diffs))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("DiffList", diffs))
+	default:
+		if len(diffs) == 0 {
+			fmt.Fprintln(&buf, "No differences found.")
+			break
+		}
+		cc.diffTableRender(&buf, diffs)
+	}
+	cc.write(buf.Bytes())
+}
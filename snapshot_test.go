@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []Record
+		after  []Record
+		order  string
+		want   []DiffRecord
+	}{
+		{
+			name:   "unchanged counts are dropped",
+			before: []Record{{Namespace: "default", GroupVersion: "v1", Kind: "Pod", Count: 3}},
+			after:  []Record{{Namespace: "default", GroupVersion: "v1", Kind: "Pod", Count: 3}},
+			want:   []DiffRecord{},
+		},
+		{
+			name:   "added and removed kinds",
+			before: []Record{{Namespace: "default", GroupVersion: "v1", Kind: "Pod", Count: 3}},
+			after:  []Record{{Namespace: "default", GroupVersion: "apps/v1", Kind: "Deployment", Count: 2}},
+			want: []DiffRecord{
+				{Namespace: "default", GroupVersion: "apps/v1", Kind: "Deployment", Before: 0, After: 2, Change: 2},
+				{Namespace: "default", GroupVersion: "v1", Kind: "Pod", Before: 3, After: 0, Change: -3},
+			},
+		},
+		{
+			name: "breakdowns and clusters are compared apart",
+			before: []Record{
+				{Cluster: "a", Namespace: "ci", GroupVersion: "batch/v1", Kind: "Job", Breakdown: map[string]string{"status": "active"}, Count: 1},
+				{Cluster: "b", Namespace: "ci", GroupVersion: "batch/v1", Kind: "Job", Breakdown: map[string]string{"status": "active"}, Count: 1},
+			},
+			after: []Record{
+				{Cluster: "a", Namespace: "ci", GroupVersion: "batch/v1", Kind: "Job", Breakdown: map[string]string{"status": "failed"}, Count: 1},
+				{Cluster: "b", Namespace: "ci", GroupVersion: "batch/v1", Kind: "Job", Breakdown: map[string]string{"status": "active"}, Count: 1},
+			},
+			want: []DiffRecord{
+				{Cluster: "a", Namespace: "ci", GroupVersion: "batch/v1", Kind: "Job", Breakdown: map[string]string{"status": "active"}, Before: 1, After: 0, Change: -1},
+				{Cluster: "a", Namespace: "ci", GroupVersion: "batch/v1", Kind: "Job", Breakdown: map[string]string{"status": "failed"}, Before: 0, After: 1, Change: 1},
+			},
+		},
+		{
+			name: "largest changes first in descending order",
+			before: []Record{
+				{Namespace: "a", GroupVersion: "v1", Kind: "Pod", Count: 10},
+				{Namespace: "b", GroupVersion: "v1", Kind: "Pod", Count: 10},
+			},
+			after: []Record{
+				{Namespace: "a", GroupVersion: "v1", Kind: "Pod", Count: 11},
+				{Namespace: "b", GroupVersion: "v1", Kind: "Pod", Count: 2},
+			},
+			order: "desc",
+			want: []DiffRecord{
+				{Namespace: "b", GroupVersion: "v1", Kind: "Pod", Before: 10, After: 2, Change: -8},
+				{Namespace: "a", GroupVersion: "v1", Kind: "Pod", Before: 10, After: 11, Change: 1},
+			},
+		},
+		{
+			name: "repeated rows are summed",
+			before: []Record{
+				{GroupVersion: "v1", Kind: "Secret", Count: 1},
+				{GroupVersion: "v1", Kind: "Secret", Count: 2},
+			},
+			after: []Record{{GroupVersion: "v1", Kind: "Secret", Count: 5}},
+			want:  []DiffRecord{{GroupVersion: "v1", Kind: "Secret", Before: 3, After: 5, Change: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffRecords(tt.before, tt.after, tt.order)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffRecords() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameScope(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *SnapshotScope
+		want bool
+	}{
+		{name: "unrecorded", a: nil, b: &SnapshotScope{AllNamespaces: true}, want: true},
+		{name: "equal", a: &SnapshotScope{Namespace: "ci"}, b: &SnapshotScope{Namespace: "ci"}, want: true},
+		{name: "aggregated and per namespace", a: &SnapshotScope{AllNamespaces: true}, b: &SnapshotScope{}, want: false},
+		{name: "other namespace", a: &SnapshotScope{Namespace: "ci"}, b: &SnapshotScope{Namespace: "prod"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameScope(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
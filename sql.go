@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func sqlJSON(v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return "NULL"
+	}
+	return sqlQuote(string(b))
+}
+
+func (cc *CounterController) sqlRender(w io.Writer, records []Record) {
+	if !sqlIdentifier.MatchString(cc.sqlTable) {
+		fmt.Fprintf(os.Stderr, "[Oh...] Invalid SQL table name: '%s'\n", cc.sqlTable)
+		exit(exitFailure)
+	}
+
+	fmt.Fprintf(w, `CREATE TABLE IF NOT EXISTS %s (
+  collected_at TIMESTAMP NOT NULL,
+  cluster VARCHAR(253) NOT NULL,
+  namespace VARCHAR(253) NOT NULL,
+  group_version VARCHAR(253) NOT NULL,
+  kind VARCHAR(253) NOT NULL,
+  breakdown TEXT,
+  count BIGINT NOT NULL,
+  metrics TEXT
+);
+`, cc.sqlTable)
+
+	collectedAt := sqlQuote(time.Now().UTC().Format("2006-01-02 15:04:05"))
+	for _, record := range records {
+		var breakdown, metrics interface{}
+		if len(record.Breakdown) > 0 {
+			breakdown = record.Breakdown
+		}
+		if len(record.Metrics) > 0 {
+			metrics = record.Metrics
+		}
+		fmt.Fprintf(w, "INSERT INTO %s (collected_at, cluster, namespace, group_version, kind, breakdown, count, metrics) VALUES (%s, %s, %s, %s, %s, %s, %d, %s);\n",
+			cc.sqlTable,
+			collectedAt,
+			sqlQuote(record.Cluster),
+			sqlQuote(record.Namespace),
+			sqlQuote(record.GroupVersion),
+			sqlQuote(record.Kind),
+			sqlJSON(breakdown),
+			record.Count,
+			sqlJSON(metrics),
+		)
+	}
+}
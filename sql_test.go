@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestSQLQuote(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "", want: "''"},
+		{s: "kube-system", want: "'kube-system'"},
+		{s: "it's", want: "'it''s'"},
+		{s: "'; DROP TABLE kubectl_count; --", want: "'''; DROP TABLE kubectl_count; --'"},
+		{s: `back\slash`, want: `'back\slash'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := sqlQuote(tt.s); got != tt.want {
+				t.Errorf("sqlQuote(%q) = %s, want %s", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{name: "nil", v: nil, want: "NULL"},
+		{name: "nil map", v: map[string]string(nil), want: "NULL"},
+		{name: "map", v: map[string]int{"restarts": 2}, want: `'{"restarts":2}'`},
+		{name: "quotes", v: map[string]string{"reason": "it's"}, want: `'{"reason":"it''s"}'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlJSON(tt.v); got != tt.want {
+				t.Errorf("sqlJSON(%v) = %s, want %s", tt.v, got, tt.want)
+			}
+		})
+	}
+}
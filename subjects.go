@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+type SubjectRecord struct {
+	Kind                string `json:"kind" yaml:"kind"`
+	Name                string `json:"name" yaml:"name"`
+	Namespace           string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	RoleBindings        int    `json:"roleBindings" yaml:"roleBindings"`
+	ClusterRoleBindings int    `json:"clusterRoleBindings" yaml:"clusterRoleBindings"`
+}
+
+func (sr SubjectRecord) total() int {
+	return sr.RoleBindings + sr.ClusterRoleBindings
+}
+
+type subject struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+type binding struct {
+	cluster  bool
+	subjects []subject
+}
+
+type SubjectMap struct {
+	lock sync.Mutex
+	m    map[types.UID]binding
+}
+
+func NewSubjectMap() *SubjectMap {
+	return &SubjectMap{
+		m: map[types.UID]binding{},
+	}
+}
+
+func (sm *SubjectMap) observe(obj interface{}) {
+	o, ok := obj.(*unstructured.Unstructured)
+	if !ok || groupOf(o) != "rbac.authorization.k8s.io" {
+		return
+	}
+	if o.GetKind() != "RoleBinding" && o.GetKind() != "ClusterRoleBinding" {
+		return
+	}
+
+	b := binding{cluster: o.GetKind() == "ClusterRoleBinding"}
+	items, _, _ := unstructured.NestedSlice(o.Object, "subjects")
+	for _, item := range items {
+		s, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(s, "kind")
+		name, _, _ := unstructured.NestedString(s, "name")
+		namespace, _, _ := unstructured.NestedString(s, "namespace")
+		// users and groups are cluster-wide identities.
+		if kind != "ServiceAccount" {
+			namespace = ""
+		}
+		b.subjects = append(b.subjects, subject{Kind: kind, Name: name, Namespace: namespace})
+	}
+
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	sm.m[o.GetUID()] = b
+}
+
+func (sm *SubjectMap) Handler(id string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: sm.observe,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			sm.observe(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			o, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			sm.lock.Lock()
+			defer sm.lock.Unlock()
+			delete(sm.m, o.GetUID())
+		},
+	}
+}
+
+func (sm *SubjectMap) GetRecords(order string) []SubjectRecord {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+
+	records := map[subject]*SubjectRecord{}
+	for _, b := range sm.m {
+		// a subject listed twice in a binding is still bound once.
+		seen := map[subject]bool{}
+		for _, s := range b.subjects {
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			r, ok := records[s]
+			if !ok {
+				r = &SubjectRecord{Kind: s.Kind, Name: s.Name, Namespace: s.Namespace}
+				records[s] = r
+			}
+			if b.cluster {
+				r.ClusterRoleBindings++
+			} else {
+				r.RoleBindings++
+			}
+		}
+	}
+
+	ret := make([]SubjectRecord, 0, len(records))
+	for _, r := range records {
+		ret = append(ret, *r)
+	}
+
+	desc := isDesc(order)
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].total() != ret[j].total() {
+			if desc {
+				return ret[i].total() > ret[j].total()
+			}
+			return ret[i].total() < ret[j].total()
+		}
+		if ret[i].Kind != ret[j].Kind {
+			return ret[i].Kind < ret[j].Kind
+		}
+		if ret[i].Namespace != ret[j].Namespace {
+			return ret[i].Namespace < ret[j].Namespace
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func (cc *CounterController) subjects(s string) (*SubjectMap, error) {
+	defer cc.cancel()
+
+	subjectMap := NewSubjectMap()
+	if _, err := cc.sync(s, subjectMap.Handler); err != nil && !errors.Is(err, errInterrupted) {
+		return nil, err
+	}
+	return subjectMap, nil
+}
+
+func (cc *CounterController) subjectTableRender(w io.Writer, records []SubjectRecord) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"Subject", "Namespace", "Name", "RoleBindings", "ClusterRoleBindings", "Total"})
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
+	table.SetRowLine(true)
+
+	for _, record := range records {
+		table.Append([]string{
+			record.Kind,
+			record.Namespace,
+			record.Name,
+			humanizeCount(record.RoleBindings, cc.humanize),
+			humanizeCount(record.ClusterRoleBindings, cc.humanize),
+			humanizeCount(record.total(), cc.humanize),
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderSubjects(kinds, order, output string) {
+	subjectMap, err := cc.subjects(kinds)
+	cc.summarize()
+	if err != nil {
+		logger.Error("list resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	records := subjectMap.GetRecords(order)
+	if len(records) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("SubjectList", records))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("SubjectList", records))
+	default:
+		cc.subjectTableRender(&buf, records)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
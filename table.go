@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/olekukonko/tablewriter"
+)
+
+var tableStyles = map[string]bool{
+	"grid":       true,
+	"rounded":    true,
+	"borderless": true,
+	"plain":      true,
+}
+
+// Table applies the --table-style and the other table flags to the tables of every output.
+type Table struct {
+	*tablewriter.Table
+	w       io.Writer
+	buf     bytes.Buffer
+	style   string
+	noMerge bool
+	compact bool
+	// cells wider than maxWidth are cut with an ellipsis, 0 means unlimited.
+	maxWidth int
+}
+
+func (cc *CounterController) newTable(w io.Writer) *Table {
+	t := &Table{w: w, style: cc.tableStyle, noMerge: cc.noMergeCells, compact: cc.compact, maxWidth: cc.maxColWidth}
+	t.Table = tablewriter.NewWriter(&t.buf)
+	// cells are wrapped past the column width as they are appended.
+	if t.maxWidth > 0 {
+		t.SetColWidth(t.maxWidth)
+	}
+	return t
+}
+
+func (t *Table) truncate(row []string) []string {
+	if t.maxWidth <= 0 {
+		return row
+	}
+	ret := make([]string, len(row))
+	for i, cell := range row {
+		ret[i] = runewidth.Truncate(cell, t.maxWidth, "…")
+	}
+	return ret
+}
+
+func (t *Table) Append(row []string) {
+	t.Table.Append(t.truncate(row))
+}
+
+func (t *Table) Rich(row []string, colors []tablewriter.Colors) {
+	t.Table.Rich(t.truncate(row), colors)
+}
+
+func (t *Table) Render() {
+	// every row is whole when copied out of the table, merged cells can't be
+	// told apart without the lines between the rows either.
+	if t.noMerge || t.compact {
+		t.SetAutoMergeCells(false)
+	}
+	if t.compact {
+		t.SetRowLine(false)
+	}
+	switch t.style {
+	case "rounded":
+		t.SetCenterSeparator("┼")
+		t.SetColumnSeparator("│")
+		t.SetRowSeparator("─")
+	case "borderless":
+		// rows are only split by the line under the header.
+		t.SetBorder(false)
+		t.SetRowLine(false)
+	case "plain":
+		// columns only split by spaces and every row whole, like kubectl get.
+		t.SetBorder(false)
+		t.SetHeaderLine(false)
+		t.SetRowLine(false)
+		t.SetAutoMergeCells(false)
+		t.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		t.SetCenterSeparator("")
+		t.SetColumnSeparator("")
+		t.SetRowSeparator("")
+		t.SetNoWhiteSpace(true)
+		t.SetTablePadding("   ")
+		if t.compact {
+			t.SetTablePadding(" ")
+		}
+	}
+	t.Table.Render()
+
+	out := t.buf.String()
+	if t.style == "rounded" {
+		out = joinBoxLines(out)
+	}
+	io.WriteString(t.w, out)
+}
+
+// boxRune is a rune of the table output with the escape sequences coloring it.
+type boxRune struct {
+	escapes string
+	r       rune
+}
+
+func splitBoxRunes(line string) []boxRune {
+	var ret []boxRune
+	var escapes strings.Builder
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\x1b' {
+			for ; i < len(runes) && runes[i] != 'm'; i++ {
+				escapes.WriteRune(runes[i])
+			}
+			escapes.WriteRune('m')
+			continue
+		}
+		ret = append(ret, boxRune{escapes: escapes.String(), r: runes[i]})
+		escapes.Reset()
+	}
+	if escapes.Len() > 0 {
+		ret = append(ret, boxRune{escapes: escapes.String()})
+	}
+	return ret
+}
+
+// tablewriter draws every joint with the center separator, joinBoxLines
+// picks the corner, tee or cross matching the lines around each of them.
+func joinBoxLines(s string) string {
+	lines := strings.Split(s, "\n")
+	grid := make([][]boxRune, len(lines))
+	for i, line := range lines {
+		grid[i] = splitBoxRunes(line)
+	}
+	at := func(i, j int) rune {
+		if i < 0 || i >= len(grid) || j < 0 || j >= len(grid[i]) {
+			return ' '
+		}
+		return grid[i][j].r
+	}
+
+	joints := map[[4]bool]rune{
+		// up, down, left, right
+		{false, true, false, true}: '╭',
+		{false, true, true, false}: '╮',
+		{true, false, false, true}: '╰',
+		{true, false, true, false}: '╯',
+		{true, true, false, true}:  '├',
+		{true, true, true, false}:  '┤',
+		{false, true, true, true}:  '┬',
+		{true, false, true, true}:  '┴',
+		{true, true, true, true}:   '┼',
+		{true, true, false, false}: '│',
+		{false, false, true, true}: '─',
+	}
+	var b strings.Builder
+	for i := range grid {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for j, br := range grid[i] {
+			b.WriteString(br.escapes)
+			if br.r == '┼' {
+				key := [4]bool{
+					at(i-1, j) == '│' || at(i-1, j) == '┼',
+					at(i+1, j) == '│' || at(i+1, j) == '┼',
+					at(i, j-1) == '─' || at(i, j-1) == '┼',
+					at(i, j+1) == '─' || at(i, j+1) == '┼',
+				}
+				if joint, ok := joints[key]; ok {
+					br.r = joint
+				}
+			}
+			if br.r != 0 {
+				b.WriteRune(br.r)
+			}
+		}
+	}
+	return b.String()
+}
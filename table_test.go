@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJoinBoxLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{
+			name: "grid",
+			in: []string{
+				"┼───┼───┼",
+				"│ a │ b │",
+				"┼───┼───┼",
+				"│ 1 │ 2 │",
+				"┼───┼───┼",
+			},
+			want: []string{
+				"╭───┬───╮",
+				"│ a │ b │",
+				"├───┼───┤",
+				"│ 1 │ 2 │",
+				"╰───┴───╯",
+			},
+		},
+		{
+			name: "colored cells",
+			in: []string{
+				"┼───┼───┼",
+				"│ \x1b[31ma\x1b[0m │ b │",
+				"┼───┼───┼",
+			},
+			want: []string{
+				"╭───┬───╮",
+				"│ \x1b[31ma\x1b[0m │ b │",
+				"╰───┴───╯",
+			},
+		},
+		{
+			name: "merged cells",
+			in: []string{
+				"┼───┼───┼",
+				"│ a │ 1 │",
+				"┼   ┼───┼",
+				"│   │ 2 │",
+				"┼───┼───┼",
+			},
+			want: []string{
+				"╭───┬───╮",
+				"│ a │ 1 │",
+				"│   ├───┤",
+				"│   │ 2 │",
+				"╰───┴───╯",
+			},
+		},
+		{
+			name: "text",
+			in:   []string{"a ┼ b"},
+			want: []string{"a ┼ b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := joinBoxLines(strings.Join(tt.in, "\n"))
+			if want := strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("joinBoxLines() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
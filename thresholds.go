@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type Thresholds struct {
+	Items []ThresholdItem `json:"items" yaml:"items"`
+}
+
+type ThresholdItem struct {
+	Namespace    string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	GroupVersion string `json:"groupVersion,omitempty" yaml:"groupVersion,omitempty"`
+	Kind         string `json:"kind" yaml:"kind"`
+	Warning      int    `json:"warning,omitempty" yaml:"warning,omitempty"`
+	Critical     int    `json:"critical,omitempty" yaml:"critical,omitempty"`
+}
+
+func loadThresholds(path string) (*Thresholds, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	thresholds := &Thresholds{}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		err = json.Unmarshal(b, thresholds)
+	} else {
+		err = yaml.Unmarshal(b, thresholds)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range thresholds.Items {
+		if item.Kind == "" {
+			return nil, fmt.Errorf("kind of threshold #%d is required", i)
+		}
+		// the exporter labels the counts with the kind as served, e.g. Pod or
+		// StatefulSet, so the CLI and the alerts only agree on that spelling.
+		if !canonicalKind(item.Kind) {
+			return nil, fmt.Errorf("kind %q of threshold #%d must be spelled like the kind of its objects, e.g. Pod or StatefulSet", item.Kind, i)
+		}
+		if item.Warning < 0 || item.Critical < 0 || item.Warning == 0 && item.Critical == 0 {
+			return nil, fmt.Errorf("threshold #%d of %s needs a positive warning or critical limit", i, item.Kind)
+		}
+		if item.Warning > 0 && item.Critical > 0 && item.Critical < item.Warning {
+			return nil, fmt.Errorf("critical limit of threshold #%d of %s is below its warning limit", i, item.Kind)
+		}
+	}
+	return thresholds, nil
+}
+
+func canonicalKind(kind string) bool {
+	for i, r := range kind {
+		if i == 0 && !unicode.IsUpper(r) || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type Breach struct {
+	Namespace    string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	GroupVersion string `json:"groupVersion,omitempty" yaml:"groupVersion,omitempty"`
+	Kind         string `json:"kind" yaml:"kind"`
+	Severity     string `json:"severity" yaml:"severity"`
+	Count        int    `json:"count" yaml:"count"`
+	Limit        int    `json:"limit" yaml:"limit"`
+}
+
+func (t *Thresholds) kinds() string {
+	var kinds []string
+	seen := map[string]bool{}
+	for _, item := range t.Items {
+		kind := strings.ToLower(item.Kind)
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return strings.Join(kinds, ",")
+}
+
+func (item ThresholdItem) match(record Record) bool {
+	if record.Kind != item.Kind {
+		return false
+	}
+	if item.GroupVersion != "" && record.GroupVersion != item.GroupVersion {
+		return false
+	}
+	return item.Namespace == "" || record.Namespace == item.Namespace
+}
+
+func (t *Thresholds) breaches(records []Record) []Breach {
+	var breaches []Breach
+	for _, item := range t.Items {
+		count := 0
+		for _, record := range records {
+			if item.match(record) {
+				count += record.Count
+			}
+		}
+
+		breach := Breach{Namespace: item.Namespace, GroupVersion: item.GroupVersion, Kind: item.Kind, Count: count}
+		switch {
+		case item.Critical > 0 && count > item.Critical:
+			breach.Severity, breach.Limit = "critical", item.Critical
+		case item.Warning > 0 && count > item.Warning:
+			breach.Severity, breach.Limit = "warning", item.Warning
+		default:
+			continue
+		}
+		breaches = append(breaches, breach)
+	}
+	return breaches
+}
+
+// a breach of a limit summed over namespaces colors the -A rows only.
+func (cc *CounterController) breachOf(record Record) string {
+	severity := ""
+	for _, breach := range cc.breaches {
+		if record.Kind != breach.Kind || record.Namespace != breach.Namespace {
+			continue
+		}
+		if breach.GroupVersion != "" && record.GroupVersion != breach.GroupVersion {
+			continue
+		}
+		if severity != "critical" {
+			severity = breach.Severity
+		}
+	}
+	return severity
+}
+
+func (cc *CounterController) enforceThresholds() {
+	code := 0
+	for _, breach := range cc.breaches {
+		name := breach.Kind
+		if breach.Namespace != "" {
+			name = breach.Namespace + "/" + name
+		}
+		fmt.Fprintf(os.Stderr, "[Threshold] %s %s count %d is over the %s limit %d\n", breach.Severity, name, breach.Count, breach.Severity, breach.Limit)
+		if breach.Severity == "critical" {
+			code = exitCritical
+		} else if code == 0 {
+			code = exitWarning
+		}
+	}
+	if code != 0 {
+		exit(code)
+	}
+}
+
+type PrometheusRule struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   map[string]string  `yaml:"metadata"`
+	Spec       PrometheusRuleSpec `yaml:"spec"`
+}
+
+type PrometheusRuleSpec struct {
+	Groups []PrometheusRuleGroup `yaml:"groups"`
+}
+
+type PrometheusRuleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []AlertRule `yaml:"rules"`
+}
+
+type AlertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+func (item ThresholdItem) expr() string {
+	// the exporter labels every series with the namespace, group_version and
+	// kind of the counts, a threshold without namespace limits their sum.
+	matchers := []string{fmt.Sprintf("kind=%q", item.Kind)}
+	if item.GroupVersion != "" {
+		matchers = append(matchers, fmt.Sprintf("group_version=%q", item.GroupVersion))
+	}
+	if item.Namespace != "" {
+		matchers = append([]string{fmt.Sprintf("namespace=%q", item.Namespace)}, matchers...)
+	}
+	return fmt.Sprintf("sum(%s{%s})", metricName, strings.Join(matchers, ","))
+}
+
+func (item ThresholdItem) rules(duration time.Duration) []AlertRule {
+	scope := "the cluster"
+	if item.Namespace != "" {
+		scope = "namespace " + item.Namespace
+	}
+
+	var rules []AlertRule
+	for _, limit := range []struct {
+		severity string
+		value    int
+	}{{"warning", item.Warning}, {"critical", item.Critical}} {
+		if limit.value <= 0 {
+			continue
+		}
+		labels := map[string]string{"severity": limit.severity, "kind": item.Kind}
+		if item.Namespace != "" {
+			labels["namespace"] = item.Namespace
+		}
+		rule := AlertRule{
+			Alert:  "KubectlCount" + item.Kind + "High",
+			Expr:   fmt.Sprintf("%s > %d", item.expr(), limit.value),
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("Too many %s objects in %s", item.Kind, scope),
+				"description": fmt.Sprintf("{{ $value }} %s objects in %s, the %s limit is %d.", item.Kind, scope, limit.severity, limit.value),
+			},
+		}
+		if duration > 0 {
+			rule.For = promDuration(duration)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// older prometheus releases only parse durations with a single unit like 10m.
+func promDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+func (t *Thresholds) PrometheusRule(name string, duration time.Duration) PrometheusRule {
+	rules := make([]AlertRule, 0, 2*len(t.Items))
+	for _, item := range t.Items {
+		rules = append(rules, item.rules(duration)...)
+	}
+	return PrometheusRule{
+		APIVersion: "monitoring.coreos.com/v1",
+		Kind:       "PrometheusRule",
+		Metadata:   map[string]string{"name": name},
+		Spec: PrometheusRuleSpec{
+			Groups: []PrometheusRuleGroup{{Name: name, Rules: rules}},
+		},
+	}
+}
+
+var genAlertsCmd = &cobra.Command{
+	Use:   "gen-alerts <thresholds>",
+	Short: "Generate a PrometheusRule alerting on the exported counts crossing the limits of a thresholds file.",
+	Example: `  # keep the alerts of the serve exporter in sync with the thresholds of the CLI.
+  kubectl count gen-alerts thresholds.yaml | kubectl apply -n monitoring -f -`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("rule-name")
+		duration, _ := cmd.Flags().GetDuration("for")
+		// Prometheus durations are rendered in whole seconds at least.
+		if duration < 0 || duration%time.Second != 0 {
+			fmt.Fprintf(os.Stderr, "[Oh...] Invalid --for %s, use 0 or whole seconds like 90s!\n", duration)
+			exit(exitFailure)
+		}
+
+		thresholds, err := loadThresholds(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to load thresholds, error: %v", err)
+			exit(exitFailure)
+		}
+		b, err := yaml.Marshal(thresholds.PrometheusRule(name, duration))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to marshal YAML data, error: %v", err)
+			exit(exitFailure)
+		}
+		fmt.Fprint(cmd.OutOrStdout(), string(b))
+	},
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThresholdItemExpr(t *testing.T) {
+	tests := []struct {
+		name string
+		item ThresholdItem
+		want string
+	}{
+		{
+			name: "summed over namespaces",
+			item: ThresholdItem{Kind: "Secret"},
+			want: `sum(kubectl_count_resources{kind="Secret"})`,
+		},
+		{
+			name: "group version",
+			item: ThresholdItem{Kind: "Deployment", GroupVersion: "apps/v1"},
+			want: `sum(kubectl_count_resources{kind="Deployment",group_version="apps/v1"})`,
+		},
+		{
+			name: "namespace",
+			item: ThresholdItem{Namespace: "ci", Kind: "Job", GroupVersion: "batch/v1"},
+			want: `sum(kubectl_count_resources{namespace="ci",kind="Job",group_version="batch/v1"})`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.item.expr(); got != tt.want {
+				t.Errorf("expr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholdItemRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     ThresholdItem
+		duration time.Duration
+		want     []AlertRule
+	}{
+		{
+			name:     "warning and critical",
+			item:     ThresholdItem{Namespace: "ci", Kind: "Job", Warning: 500, Critical: 1000},
+			duration: 10 * time.Minute,
+			want: []AlertRule{
+				{
+					Alert:  "KubectlCountJobHigh",
+					Expr:   `sum(kubectl_count_resources{namespace="ci",kind="Job"}) > 500`,
+					For:    "10m",
+					Labels: map[string]string{"severity": "warning", "kind": "Job", "namespace": "ci"},
+					Annotations: map[string]string{
+						"summary":     "Too many Job objects in namespace ci",
+						"description": "{{ $value }} Job objects in namespace ci, the warning limit is 500.",
+					},
+				},
+				{
+					Alert:  "KubectlCountJobHigh",
+					Expr:   `sum(kubectl_count_resources{namespace="ci",kind="Job"}) > 1000`,
+					For:    "10m",
+					Labels: map[string]string{"severity": "critical", "kind": "Job", "namespace": "ci"},
+					Annotations: map[string]string{
+						"summary":     "Too many Job objects in namespace ci",
+						"description": "{{ $value }} Job objects in namespace ci, the critical limit is 1000.",
+					},
+				},
+			},
+		},
+		{
+			name: "critical only fires right away",
+			item: ThresholdItem{Kind: "Secret", GroupVersion: "v1", Critical: 20000},
+			want: []AlertRule{
+				{
+					Alert:  "KubectlCountSecretHigh",
+					Expr:   `sum(kubectl_count_resources{kind="Secret",group_version="v1"}) > 20000`,
+					Labels: map[string]string{"severity": "critical", "kind": "Secret"},
+					Annotations: map[string]string{
+						"summary":     "Too many Secret objects in the cluster",
+						"description": "{{ $value }} Secret objects in the cluster, the critical limit is 20000.",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.item.rules(tt.duration)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 2 * time.Hour, want: "2h"},
+		{d: 90 * time.Minute, want: "90m"},
+		{d: 90 * time.Second, want: "90s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			if got := promDuration(tt.d); got != tt.want {
+				t.Errorf("promDuration(%s) = %s, want %s", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadThresholds(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "yaml",
+			content: "items:\n  - kind: StatefulSet\n    warning: 10\n",
+		},
+		{
+			name:    "json",
+			content: `{"items": [{"kind": "Pod", "namespace": "ci", "critical": 100}]}`,
+		},
+		{
+			name:    "lowercase kind",
+			content: "items:\n  - kind: pod\n    warning: 10\n",
+			wantErr: `kind "pod" of threshold #0 must be spelled like the kind of its objects`,
+		},
+		{
+			name:    "resource name",
+			content: "items:\n  - kind: pods.v1\n    warning: 10\n",
+			wantErr: `kind "pods.v1" of threshold #0`,
+		},
+		{
+			name:    "missing kind",
+			content: "items:\n  - warning: 10\n",
+			wantErr: "kind of threshold #0 is required",
+		},
+		{
+			name:    "no limit",
+			content: "items:\n  - kind: Pod\n",
+			wantErr: "needs a positive warning or critical limit",
+		},
+		{
+			name:    "critical below warning",
+			content: "items:\n  - kind: Pod\n    warning: 10\n    critical: 5\n",
+			wantErr: "is below its warning limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "thresholds")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := loadThresholds(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("loadThresholds() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadThresholds() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
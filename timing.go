@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Timing struct {
+	lock      sync.Mutex
+	started   time.Time
+	discovery time.Duration
+	syncs     map[string]time.Duration
+	objects   map[string]int
+}
+
+func NewTiming() *Timing {
+	return &Timing{
+		started: time.Now(),
+		syncs:   map[string]time.Duration{},
+		objects: map[string]int{},
+	}
+}
+
+func (t *Timing) ObserveDiscovery(d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.discovery = d
+}
+
+func (t *Timing) ObserveSync(id string, d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.syncs[id] = d
+}
+
+func (t *Timing) SyncDuration(id string) time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.syncs[id]
+}
+
+func (t *Timing) ObserveObject(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.objects[id]++
+}
+
+func (t *Timing) Objects(id string) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.objects[id]
+}
+
+func (t *Timing) Report(w io.Writer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	ids := make([]string, 0, len(t.syncs))
+	for id := range t.syncs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return t.syncs[ids[i]] > t.syncs[ids[j]]
+	})
+
+	total := 0
+	fmt.Fprintf(w, "[Timing] discovery: %v\n", t.discovery.Round(time.Millisecond))
+	for _, id := range ids {
+		kind, groupVersion := splitID(id)
+		fmt.Fprintf(w, "[Timing] sync %s (%s): %v, %d objects\n", kind, groupVersion, t.syncs[id].Round(time.Millisecond), t.objects[id])
+		total += t.objects[id]
+	}
+	fmt.Fprintf(w, "[Timing] total: %d kinds, %d objects in %v\n", len(ids), total, time.Since(t.started).Round(time.Millisecond))
+}
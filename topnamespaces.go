@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// kinds listed in the composition column of the table, json and yaml have all.
+const compositionKinds = 5
+
+type KindCount struct {
+	GroupVersion string `json:"groupVersion" yaml:"groupVersion"`
+	Kind         string `json:"kind" yaml:"kind"`
+	Count        int    `json:"count" yaml:"count"`
+}
+
+type TopNamespace struct {
+	Namespace string      `json:"namespace" yaml:"namespace"`
+	Count     int         `json:"count" yaml:"count"`
+	Kinds     []KindCount `json:"kinds" yaml:"kinds"`
+}
+
+var topNamespacesCmd = &cobra.Command{
+	Use:   "top-namespaces [kinds]",
+	Short: "Rank namespaces by their total number of objects, of every namespaced kind by default.",
+	Example: `  # the ten largest namespaces of the cluster and what they are made of.
+  kubectl count top-namespaces
+
+  # rank the namespaces by their workloads only.
+  kubectl count top-namespaces deploy,sts,ds,cj --top 20`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output-format")
+		top, _ := cmd.Flags().GetInt("top")
+
+		ctr := mustController(cmd)
+		kinds := mustKinds(cmd, args)
+		if kinds == "" {
+			var err error
+			if kinds, err = ctr.namespacedKinds(); err != nil {
+				logger.Error("discover resources failed", err)
+				fmt.Fprintf(os.Stderr, "[Oh...] Failed to discover resources, error: %v", err)
+				exit(exitCode(err))
+			}
+		}
+		ctr.RenderTopNamespaces(kinds, top, format)
+		exit(0)
+	},
+}
+
+func (cc *CounterController) namespacedKinds() (string, error) {
+	agvs, err := cc.serverResources()
+	if err != nil {
+		return "", err
+	}
+
+	var kinds []string
+	for _, agv := range agvs {
+		r := agv.resource
+		if !r.Namespaced || !agv.listable() || strings.Contains(r.Name, "/") {
+			continue
+		}
+		// events.k8s.io serves the events of the core group once more.
+		if r.Group == "events.k8s.io" {
+			continue
+		}
+		kinds = append(kinds, fmt.Sprintf("%s.%s", r.Name, r.Group))
+	}
+	return strings.Join(kinds, ","), nil
+}
+
+func topNamespaces(records []Record, top int) []TopNamespace {
+	namespaces := map[string]*TopNamespace{}
+	for _, record := range records {
+		if record.Namespace == "" || record.Count == 0 {
+			continue
+		}
+		tn, ok := namespaces[record.Namespace]
+		if !ok {
+			tn = &TopNamespace{Namespace: record.Namespace}
+			namespaces[record.Namespace] = tn
+		}
+		tn.Count += record.Count
+
+		// breakdowns split a kind into several records.
+		merged := false
+		for i := range tn.Kinds {
+			if tn.Kinds[i].Kind == record.Kind && tn.Kinds[i].GroupVersion == record.GroupVersion {
+				tn.Kinds[i].Count += record.Count
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			tn.Kinds = append(tn.Kinds, KindCount{GroupVersion: record.GroupVersion, Kind: record.Kind, Count: record.Count})
+		}
+	}
+
+	ret := make([]TopNamespace, 0, len(namespaces))
+	for _, tn := range namespaces {
+		sort.SliceStable(tn.Kinds, func(i, j int) bool {
+			if tn.Kinds[i].Count != tn.Kinds[j].Count {
+				return tn.Kinds[i].Count > tn.Kinds[j].Count
+			}
+			return tn.Kinds[i].Kind < tn.Kinds[j].Kind
+		})
+		ret = append(ret, *tn)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Namespace < ret[j].Namespace
+	})
+	if top > 0 && len(ret) > top {
+		ret = ret[:top]
+	}
+	return ret
+}
+
+func (cc *CounterController) topNamespacesTableRender(w io.Writer, namespaces []TopNamespace) {
+	table := cc.newTable(w)
+	table.SetHeader([]string{"Rank", "Namespace", "Count", "Composition"})
+	table.SetAutoFormatHeaders(false)
+	table.SetRowLine(true)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_DEFAULT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+
+	for i, tn := range namespaces {
+		var parts []string
+		for j, kc := range tn.Kinds {
+			if j == compositionKinds {
+				parts = append(parts, fmt.Sprintf("%d more", len(tn.Kinds)-j))
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s %s", kc.Kind, humanizeCount(kc.Count, cc.humanize)))
+		}
+		table.Append([]string{
+			fmt.Sprintf("%d", i+1),
+			tn.Namespace,
+			humanizeCount(tn.Count, cc.humanize),
+			strings.Join(parts, ", "),
+		})
+	}
+	table.Render()
+}
+
+func (cc *CounterController) RenderTopNamespaces(kinds string, top int, output string) {
+	records, err := cc.Records(kinds, "desc", false)
+	if err != nil {
+		logger.Error("list resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to list resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	namespaces := topNamespaces(records, top)
+	if len(namespaces) <= 0 {
+		cc.empty(output)
+		if !machineReadable(output) {
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	switch output {
+	case "json", "j":
+		cc.jsonRender(&buf, newEnvelope("TopNamespaceList", namespaces))
+	case "yaml", "y":
+		cc.yamlRender(&buf, newEnvelope("TopNamespaceList", namespaces))
+	default:
+		cc.topNamespacesTableRender(&buf, namespaces)
+	}
+	cc.write(buf.Bytes())
+
+	if cc.partial() {
+		exit(exitInterrupted)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const releasesURL = "https://api.github.com/repos/chenjiandongx/kubectl-count/releases/latest"
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version, optionally checking for a newer release.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("kubectl-count version %s\n", version)
+		if check, _ := cmd.Flags().GetBool("check"); !check {
+			return
+		}
+
+		r, err := latestRelease()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to check the latest release, error: %v", err)
+			exit(exitFailure)
+		}
+		if !newerVersion(r.TagName, version) {
+			fmt.Println("You are running the latest version.")
+			return
+		}
+		fmt.Printf("A newer version %s is available, upgrade with `kubectl krew upgrade count` or `kubectl count self-update`.\n", r.TagName)
+	},
+}
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Replace the binary with the latest release, for installations not managed by krew.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := selfUpdate(); err != nil {
+			fmt.Fprintf(os.Stderr, "[Oh...] Failed to update, error: %v", err)
+			exit(exitFailure)
+		}
+	},
+}
+
+func latestRelease() (*release, error) {
+	req, err := http.NewRequest(http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, releasesURL)
+	}
+
+	r := &release{}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func parseVersion(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	var parts []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+func newerVersion(latest, current string) bool {
+	l, c := parseVersion(latest), parseVersion(current)
+	for i := 0; i < len(l) || i < len(c); i++ {
+		var a, b int
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+func selfUpdate() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+	if strings.Contains(filepath.ToSlash(executable), "/.krew/") {
+		return errors.New("the binary is managed by krew, run `kubectl krew upgrade count` instead")
+	}
+
+	r, err := latestRelease()
+	if err != nil {
+		return err
+	}
+	if !newerVersion(r.TagName, version) {
+		fmt.Printf("kubectl-count %s is already the latest version.\n", version)
+		return nil
+	}
+
+	asset := fmt.Sprintf("kubectl-count_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	url, checksumsURL := "", ""
+	for _, a := range r.Assets {
+		switch {
+		case a.Name == asset:
+			url = a.URL
+		case strings.HasSuffix(a.Name, "checksums.txt"):
+			checksumsURL = a.URL
+		}
+	}
+	if url == "" {
+		return fmt.Errorf("release %s has no %s asset", r.TagName, asset)
+	}
+	if checksumsURL == "" {
+		return fmt.Errorf("release %s has no checksums.txt asset", r.TagName)
+	}
+
+	checksums, err := download(checksumsURL)
+	if err != nil {
+		return err
+	}
+	want, err := assetChecksum(checksums, asset)
+	if err != nil {
+		return err
+	}
+	archive, err := download(url)
+	if err != nil {
+		return err
+	}
+	if got := sha256.Sum256(archive); hex.EncodeToString(got[:]) != want {
+		return fmt.Errorf("checksum mismatch for %s, expected %s, got %x", asset, want, got)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(executable), ".kubectl-count-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := extractBinary(bytes.NewReader(archive), tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	// a running binary can't be overwritten on windows, but it can be renamed.
+	old := executable + ".old"
+	os.Remove(old)
+	if err := os.Rename(executable, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), executable); err != nil {
+		os.Rename(old, executable)
+		return err
+	}
+	os.Remove(old)
+
+	fmt.Printf("kubectl-count updated from %s to %s.\n", version, r.TagName)
+	return nil
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// assetChecksum looks up the sha256 of asset in a goreleaser checksums.txt,
+// which has one "<sha256>  <name>" line per archive.
+func assetChecksum(checksums []byte, asset string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s in checksums.txt", asset)
+}
+
+func extractBinary(r io.Reader, w io.Writer) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return errors.New("kubectl-count binary not found in the archive")
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Base(header.Name)
+		if name == "kubectl-count" || name == "kubectl-count.exe" {
+			_, err := io.Copy(w, tr)
+			return err
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive func(t *testing.T) []byte
+		want    string
+		wantErr string
+	}{
+		{
+			name: "binary next to the license",
+			archive: func(t *testing.T) []byte {
+				return tarGz(t, map[string]string{"LICENSE": "MIT", "kubectl-count": "binary"})
+			},
+			want: "binary",
+		},
+		{
+			name: "windows binary in a directory",
+			archive: func(t *testing.T) []byte {
+				return tarGz(t, map[string]string{"dist/kubectl-count.exe": "exe"})
+			},
+			want: "exe",
+		},
+		{
+			name: "no binary",
+			archive: func(t *testing.T) []byte {
+				return tarGz(t, map[string]string{"LICENSE": "MIT"})
+			},
+			wantErr: "kubectl-count binary not found in the archive",
+		},
+		{
+			name: "not gzipped",
+			archive: func(t *testing.T) []byte {
+				return []byte("<html>rate limited</html>")
+			},
+			wantErr: "gzip: invalid header",
+		},
+		{
+			name: "truncated",
+			archive: func(t *testing.T) []byte {
+				b := tarGz(t, map[string]string{"kubectl-count": strings.Repeat("x", 4096)})
+				return b[:len(b)/2]
+			},
+			wantErr: "unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := extractBinary(bytes.NewReader(tt.archive(t)), &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractBinary() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractBinary() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("extractBinary() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetChecksum(t *testing.T) {
+	checksums := []byte(`9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08  kubectl-count_linux_amd64.tar.gz
+60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752  kubectl-count_darwin_arm64.tar.gz
+`)
+	tests := []struct {
+		asset   string
+		want    string
+		wantErr bool
+	}{
+		{asset: "kubectl-count_linux_amd64.tar.gz", want: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+		{asset: "kubectl-count_darwin_arm64.tar.gz", want: "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"},
+		{asset: "kubectl-count_windows_amd64.tar.gz", wantErr: true},
+		{asset: "linux_amd64.tar.gz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.asset, func(t *testing.T) {
+			got, err := assetChecksum(checksums, tt.asset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("assetChecksum(%s) error = %v, wantErr %v", tt.asset, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("assetChecksum(%s) = %s, want %s", tt.asset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{latest: "v0.2.7", current: "0.2.6", want: true},
+		{latest: "v0.3.0", current: "v0.2.10", want: true},
+		{latest: "v0.2.6", current: "0.2.6", want: false},
+		{latest: "v0.2.6", current: "0.3.0", want: false},
+		{latest: "v1.0.0", current: "1.0.0-rc.1", want: false},
+		{latest: "v1.0.1", current: "1.0", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.latest+" "+tt.current, func(t *testing.T) {
+			if got := newerVersion(tt.latest, tt.current); got != tt.want {
+				t.Errorf("newerVersion(%s, %s) = %v, want %v", tt.latest, tt.current, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type UploadTarget struct {
+	Scheme string
+	Bucket string
+	Prefix string
+	tool   string
+}
+
+func NewUploadTarget(dest string) (*UploadTarget, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "s3" && u.Scheme != "gs" || u.Host == "" {
+		return nil, fmt.Errorf("invalid destination '%s', want s3://bucket/path/ or gs://bucket/path/", dest)
+	}
+
+	// the CLIs of the clouds are used, along with whatever credentials they
+	// are set up with, e.g. SSO profiles or workload identities.
+	tools := []string{"aws"}
+	if u.Scheme == "gs" {
+		tools = []string{"gcloud", "gsutil"}
+	}
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err == nil {
+			return &UploadTarget{Scheme: u.Scheme, Bucket: u.Host, Prefix: strings.Trim(u.Path, "/"), tool: tool}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found in $PATH, it's needed to upload to %s://", strings.Join(tools, " or "), u.Scheme)
+}
+
+func (ut *UploadTarget) URL(collectedAt time.Time, ext string) string {
+	// keys sort by time, a prefix keeps the history of one cluster.
+	key := "snapshot-" + collectedAt.UTC().Format("20060102T150405Z") + "." + ext
+	if ut.Prefix != "" {
+		key = ut.Prefix + "/" + key
+	}
+	return ut.Scheme + "://" + ut.Bucket + "/" + key
+}
+
+func (ut *UploadTarget) Upload(b []byte, dest, contentType string) error {
+	var args []string
+	switch ut.tool {
+	case "aws":
+		args = []string{"s3", "cp", "-", dest, "--content-type", contentType, "--only-show-errors"}
+	case "gcloud":
+		args = []string{"storage", "cp", "-", dest, "--content-type=" + contentType}
+	default:
+		args = []string{"-q", "-h", "Content-Type:" + contentType, "cp", "-", dest}
+	}
+
+	cmd := exec.Command(ut.tool, args...)
+	cmd.Stdin = bytes.NewReader(b)
+	// stdout is kept for the snapshot itself.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", ut.tool, strings.Join(args[:2], " "), err)
+	}
+	return nil
+}
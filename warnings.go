@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ServerWarnings keeps the Warning headers of the apiserver, e.g. deprecated
+// kinds or admission warnings, rest.Config logs them through klog otherwise.
+type ServerWarnings struct {
+	lock  sync.Mutex
+	texts []string
+	seen  map[string]int
+}
+
+func NewServerWarnings() *ServerWarnings {
+	return &ServerWarnings{seen: map[string]int{}}
+}
+
+func (sw *ServerWarnings) HandleWarningHeader(code int, agent string, text string) {
+	// 299 is the only code the apiserver sends, see RFC 7234.
+	if code != 299 || text == "" {
+		return
+	}
+
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+
+	if sw.seen[text] == 0 {
+		sw.texts = append(sw.texts, text)
+	}
+	sw.seen[text]++
+}
+
+func (sw *ServerWarnings) drain() ([]string, map[string]int) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+
+	texts, seen := sw.texts, sw.seen
+	sw.texts, sw.seen = nil, map[string]int{}
+	return texts, seen
+}
+
+func (cc *CounterController) reportServerWarnings(w io.Writer) {
+	if cc.serverWarnings == nil {
+		return
+	}
+
+	texts, seen := cc.serverWarnings.drain()
+	for _, text := range texts {
+		suffix := ""
+		if seen[text] > 1 {
+			suffix = fmt.Sprintf(" (x%d)", seen[text])
+		}
+		fmt.Fprintf(w, "[Warning] the apiserver warned: %s%s\n", text, suffix)
+		cc.warn("ServerWarning", "the apiserver warned: %s", text)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/cache"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <kinds>",
+	Short: "Count resources by kind and refresh the counts as they change.",
+	Example: `  # keep the counts of pods and deployments of every namespace on screen.
+  kubectl count watch -A pods,deploy`,
+	Args: kindsArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		order, _ := cmd.Flags().GetString("order")
+		format, _ := cmd.Flags().GetString("output-format")
+		allNamespace, _ := cmd.Flags().GetBool("all-namespaces")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if stream, _ := cmd.Flags().GetBool("stream"); stream {
+			if interval <= 0 {
+				interval = defaultStreamInterval
+			}
+			mustController(cmd).Stream(mustKinds(cmd, args), order, allNamespace, interval)
+			exit(0)
+		}
+		mustController(cmd).Watch(mustKinds(cmd, args), order, format, allNamespace, interval)
+		exit(0)
+	},
+}
+
+const defaultStreamInterval = 10 * time.Second
+
+type StreamRecord struct {
+	Timestamp    time.Time         `json:"timestamp"`
+	Namespace    string            `json:"namespace"`
+	GroupVersion string            `json:"groupVersion"`
+	Kind         string            `json:"kind"`
+	Breakdown    map[string]string `json:"breakdown,omitempty"`
+	Count        int               `json:"count"`
+}
+
+func (cc *CounterController) Stream(kinds, order string, allNamespace bool, interval time.Duration) {
+	idMap, err := cc.sync(kinds, nil)
+	cc.summarize()
+	if errors.Is(err, errInterrupted) {
+		exit(exitInterrupted)
+	}
+	if err != nil {
+		logger.Error("watch resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to watch resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	// every tick emits the full set of counts, one record per line, so the
+	// consumers never have to carry state between ticks.
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		now := time.Now().UTC()
+		for _, record := range idMap.GetRecords(order, allNamespace) {
+			err := encoder.Encode(StreamRecord{
+				Timestamp:    now,
+				Namespace:    record.Namespace,
+				GroupVersion: record.GroupVersion,
+				Kind:         record.Kind,
+				Breakdown:    record.Breakdown,
+				Count:        record.Count,
+			})
+			if err != nil {
+				// the reading end of the pipe is gone.
+				logger.Error("write stream failed", err)
+				exit(exitFailure)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-cc.ctx.Done():
+			return
+		}
+	}
+}
+
+func (cc *CounterController) Watch(kinds, order, output string, allNamespace bool, interval time.Duration) {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := func(id string) cache.ResourceEventHandler {
+		return cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) { notify() },
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if len(cc.classifiers) > 0 || len(cc.analyzers) > 0 {
+					notify()
+				}
+			},
+			DeleteFunc: func(obj interface{}) { notify() },
+		}
+	}
+
+	idMap, err := cc.sync(kinds, handler)
+	cc.summarize()
+	if errors.Is(err, errInterrupted) {
+		exit(exitInterrupted)
+	}
+	if err != nil {
+		logger.Error("watch resources failed", err)
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to watch resources, error: %v", err)
+		exit(exitCode(err))
+	}
+
+	select {
+	case <-changed:
+	default:
+	}
+
+	// with an interval, changes are coalesced and the display refreshes at
+	// most once per tick.
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// counts are compared with the ones shown when the watch started.
+	baseline := map[string]int{}
+	for _, record := range idMap.GetRecords(order, allNamespace) {
+		baseline[diffKey(record)] = record.Count
+	}
+
+	for {
+		records := idMap.GetRecords(order, allNamespace)
+		for i := range records {
+			since := baseline[diffKey(records[i])]
+			records[i].Change = &Change{Since: since}
+			if since > 0 {
+				records[i].Change.Percent = float64(records[i].Count-since) / float64(since) * 100
+			}
+		}
+		cc.refresh(records, output, interval)
+		select {
+		case <-changed:
+		case <-cc.ctx.Done():
+			return
+		}
+		if tick == nil {
+			continue
+		}
+		select {
+		case <-tick:
+		case <-cc.ctx.Done():
+			return
+		}
+	}
+}
+
+type Change struct {
+	Since   int     `json:"since" yaml:"since"`
+	Percent float64 `json:"percent" yaml:"percent"`
+}
+
+func (c *Change) String() string {
+	if c == nil {
+		return ""
+	}
+	if c.Since == 0 {
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", c.Percent)
+}
+
+func (cc *CounterController) refresh(records []Record, output string, interval time.Duration) {
+	var buf bytes.Buffer
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	if tty {
+		buf.WriteString("\033[H\033[2J")
+	}
+	every := "change"
+	if interval > 0 {
+		every = interval.String()
+	}
+	fmt.Fprintf(&buf, "Every %s, last at %s\n\n", every, time.Now().Format(time.RFC3339))
+
+	switch {
+	case len(records) == 0:
+		fmt.Fprintln(&buf, "[Oh...] No Resources found!")
+	case output == "json" || output == "j":
+		cc.jsonRender(&buf, newEnvelope("RecordList", cc.withAverages(records)))
+	case output == "yaml" || output == "y":
+		cc.yamlRender(&buf, newEnvelope("RecordList", cc.withAverages(records)))
+	case output == "wide" || output == "w":
+		cc.tableRender(&buf, records, true)
+	default:
+		cc.tableRender(&buf, records, false)
+	}
+	if !tty {
+		buf.WriteString("\n")
+	}
+	os.Stdout.Write(buf.Bytes())
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type xlsxSheet struct {
+	name string
+	rows [][]interface{}
+}
+
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xlsxEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func (sheet xlsxSheet) xml() string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range sheet.rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			ref := xlsxColumn(j) + strconv.Itoa(i+1)
+			switch v := cell.(type) {
+			case int:
+				fmt.Fprintf(&sb, `<c r="%s"><v>%d</v></c>`, ref, v)
+			default:
+				fmt.Fprintf(&sb, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xlsxEscape(fmt.Sprint(v)))
+			}
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
+
+func writeXLSX(w io.Writer, sheets []xlsxSheet) error {
+	var contentTypes, workbook, rels strings.Builder
+	contentTypes.WriteString(xml.Header)
+	contentTypes.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	contentTypes.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	contentTypes.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	contentTypes.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+
+	workbook.WriteString(xml.Header)
+	workbook.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+
+	rels.WriteString(xml.Header)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	for i := range sheets {
+		n := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(sheets[i].name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+
+	files := []struct {
+		name, body string
+	}{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+	}
+	for i, sheet := range sheets {
+		files = append(files, struct{ name, body string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, file.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (cc *CounterController) xlsxRender(w io.Writer, records []Record) {
+	withCluster := false
+	for _, record := range records {
+		if record.Cluster != "" {
+			withCluster = true
+			break
+		}
+	}
+
+	breakdownColumns, metricColumns := cc.columns(records)
+	header := []interface{}{"Namespace", "GroupVersion", "Kind"}
+	if withCluster {
+		header = append([]interface{}{"Cluster"}, header...)
+	}
+	for _, column := range breakdownColumns {
+		header = append(header, column)
+	}
+	header = append(header, "Count")
+	for _, column := range metricColumns {
+		header = append(header, column)
+	}
+
+	recordsSheet := xlsxSheet{name: "Records", rows: [][]interface{}{header}}
+	for _, record := range records {
+		row := []interface{}{record.Namespace, record.GroupVersion, record.Kind}
+		if withCluster {
+			row = append([]interface{}{record.Cluster}, row...)
+		}
+		for _, column := range breakdownColumns {
+			row = append(row, record.Breakdown[column])
+		}
+		row = append(row, record.Count)
+		for _, column := range metricColumns {
+			row = append(row, record.Metrics[column])
+		}
+		recordsSheet.rows = append(recordsSheet.rows, row)
+	}
+
+	// pivot of namespaces (rows) by kinds (columns) with totals on both axes.
+	var kinds, namespaces []string
+	seenKinds, seenNamespaces := map[string]bool{}, map[string]bool{}
+	cells := map[string]map[string]int{}
+	for _, record := range records {
+		kind := record.Kind + " (" + record.GroupVersion + ")"
+		namespace := record.Namespace
+		if withCluster {
+			namespace = record.Cluster + "/" + namespace
+		}
+		if !seenKinds[kind] {
+			seenKinds[kind] = true
+			kinds = append(kinds, kind)
+		}
+		if !seenNamespaces[namespace] {
+			seenNamespaces[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+		if cells[namespace] == nil {
+			cells[namespace] = map[string]int{}
+		}
+		cells[namespace][kind] += record.Count
+	}
+	sort.Strings(namespaces)
+
+	summaryHeader := []interface{}{"Namespace"}
+	for _, kind := range kinds {
+		summaryHeader = append(summaryHeader, kind)
+	}
+	summaryHeader = append(summaryHeader, "Total")
+
+	summarySheet := xlsxSheet{name: "Summary", rows: [][]interface{}{summaryHeader}}
+	totals := map[string]int{}
+	grandTotal := 0
+	for _, namespace := range namespaces {
+		row := []interface{}{namespace}
+		total := 0
+		for _, kind := range kinds {
+			row = append(row, cells[namespace][kind])
+			total += cells[namespace][kind]
+			totals[kind] += cells[namespace][kind]
+		}
+		grandTotal += total
+		summarySheet.rows = append(summarySheet.rows, append(row, total))
+	}
+	totalRow := []interface{}{"Total"}
+	for _, kind := range kinds {
+		totalRow = append(totalRow, totals[kind])
+	}
+	summarySheet.rows = append(summarySheet.rows, append(totalRow, grandTotal))
+
+	if err := writeXLSX(w, []xlsxSheet{recordsSheet, summarySheet}); err != nil {
+		fmt.Fprintf(os.Stderr, "[Oh...] Failed to write XLSX data, error: %v", err)
+		exit(exitFailure)
+	}
+}